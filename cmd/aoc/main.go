@@ -0,0 +1,158 @@
+// Command aoc runs a day's solution without having to remember which files
+// make it up:
+//
+//	aoc run -day 15 [-part 2] [day flags...] <in file>
+//
+// Some days have tools as well as solutions, which run the same way:
+//
+//	aoc day17 disasm <in file>
+//
+// Input files are looked up in the current directory first and then in
+// the day's own directory, so `aoc run -day 15 --animate example` works
+// from the top of the repo.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage:\n  aoc run -day N [-part N] [day flags...] <in file>\n  aoc dayNN <tool> [tool flags...] [args...]\n")
+	os.Exit(2)
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "run":
+		day, part, rest, err := parseRunArgs(os.Args[2:])
+		if err != nil {
+			log.Printf("aoc run: %v", err)
+			usage()
+		}
+		dir := fmt.Sprintf("day%02d", day)
+		entry, err := findEntry(dir, fmt.Sprintf(`^d(ay)?0?%dp%d\.go$`, day, part))
+		if err != nil {
+			log.Fatalf("aoc run: %v", err)
+		}
+		os.Exit(goRun(dir, entry, rest))
+	default:
+		// aoc dayNN <tool> runs dayNN/<tool>.go.
+		dir := os.Args[1]
+		if !dayRE.MatchString(dir) || len(os.Args) < 3 {
+			usage()
+		}
+		entry, err := findEntry(dir, "^"+regexp.QuoteMeta(os.Args[2])+`\.go$`)
+		if err != nil {
+			log.Fatalf("aoc: %v", err)
+		}
+		os.Exit(goRun(dir, entry, os.Args[3:]))
+	}
+}
+
+var dayRE = regexp.MustCompile(`^day\d\d$`)
+
+// parseRunArgs picks out -day and -part, leaving everything else for the
+// day itself.
+func parseRunArgs(args []string) (day, part int, rest []string, err error) {
+	part = 1
+	for i := 0; i < len(args); i++ {
+		name, val, hasVal := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || (name != "day" && name != "part") {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasVal {
+			if i+1 == len(args) {
+				return 0, 0, nil, fmt.Errorf("-%s needs a value", name)
+			}
+			i++
+			val = args[i]
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return 0, 0, nil, fmt.Errorf("-%s: %v", name, err)
+		}
+		if name == "day" {
+			day = n
+		} else {
+			part = n
+		}
+	}
+	if day == 0 {
+		return 0, 0, nil, fmt.Errorf("-day is required")
+	}
+	return day, part, rest, nil
+}
+
+var mainRE = regexp.MustCompile(`(?m)^func main\(\)`)
+
+// findEntry returns the one file in dir matching pattern.
+func findEntry(dir, pattern string) (string, error) {
+	re := regexp.MustCompile(pattern)
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		if re.MatchString(filepath.Base(f)) {
+			return filepath.Base(f), nil
+		}
+	}
+	return "", fmt.Errorf("no file matching %s in %s", pattern, dir)
+}
+
+// goRun runs entry together with every file in dir that doesn't have a
+// main() of its own, and returns the exit code.
+func goRun(dir, entry string, args []string) int {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		log.Fatalf("aoc: %v", err)
+	}
+	cmdArgs := []string{"run", entry}
+	for _, f := range files {
+		if filepath.Base(f) == entry {
+			continue
+		}
+		src, err := os.ReadFile(f)
+		if err != nil {
+			log.Fatalf("aoc: %v", err)
+		}
+		if !mainRE.Match(src) {
+			cmdArgs = append(cmdArgs, filepath.Base(f))
+		}
+	}
+	for _, a := range args {
+		// The day runs in its own directory, so fix up paths to files
+		// that exist relative to here.
+		if !strings.HasPrefix(a, "-") {
+			if _, err := os.Stat(a); err == nil {
+				if abs, err := filepath.Abs(a); err == nil {
+					a = abs
+				}
+			}
+		}
+		cmdArgs = append(cmdArgs, a)
+	}
+
+	cmd := exec.Command("go", cmdArgs...)
+	cmd.Dir = dir
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			return exit.ExitCode()
+		}
+		log.Fatalf("aoc: %v", err)
+	}
+	return 0
+}
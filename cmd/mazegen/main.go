@@ -0,0 +1,66 @@
+// Command mazegen writes random mazes as puzzle inputs, bigger than the
+// examples, for trying the solvers out on:
+//
+//	mazegen [-format reindeer|bytes] [-width N] [-height N] [-seed N] [-braid F]
+//
+// reindeer writes a day 16 maze, with S and E; bytes writes a day 18 list
+// of falling bytes, whose first few build a maze. The same flags always
+// give the same output.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/phad/advent-of-code-2024/lib/maze"
+)
+
+func main() {
+	log.SetFlags(0)
+	format := flag.String("format", "reindeer", "what to write: reindeer (a day 16 maze) or bytes (a day 18 byte list)")
+	width := flag.Int("width", 141, "maze width, walls included; must be odd")
+	height := flag.Int("height", 141, "maze height, walls included; must be odd")
+	seed := flag.Int64("seed", 1, "random seed")
+	braid := flag.Float64("braid", 0, "fraction of dead ends to open up into loops, from 0 to 1")
+	out := flag.String("o", "", "file to write to (default stdout)")
+	flag.Parse()
+
+	o := maze.Options{W: *width, H: *height, Seed: *seed, Braid: *braid}
+	w := bufio.NewWriter(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("mazegen: %v", err)
+		}
+		defer f.Close()
+		w = bufio.NewWriter(f)
+	}
+
+	switch *format {
+	case "reindeer":
+		g, err := maze.Reindeer(o)
+		if err != nil {
+			log.Fatalf("mazegen: %v", err)
+		}
+		for _, row := range g.Cells {
+			fmt.Fprintln(w, string(row))
+		}
+	case "bytes":
+		bytes, walls, err := maze.Bytes(o)
+		if err != nil {
+			log.Fatalf("mazegen: %v", err)
+		}
+		for _, b := range bytes {
+			fmt.Fprintf(w, "%d,%d\n", b.X, b.Y)
+		}
+		log.Printf("mazegen: the first %d bytes build the maze; use -width %d -height %d -n %d", walls, o.W, o.H, walls)
+	default:
+		log.Fatalf("mazegen: unknown -format %q, want reindeer or bytes", *format)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatalf("mazegen: %v", err)
+	}
+}
@@ -2,9 +2,13 @@ package main
 
 import (
 	"bufio"
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+
+	"github.com/phad/advent-of-code-2024/lib/grid"
+	"github.com/phad/advent-of-code-2024/lib/render"
 )
 
 func readLines(f string) ([]string, error) {
@@ -30,3 +34,106 @@ func mustParseInt(s string) int64 {
 	}
 	return v
 }
+
+func assert(s string, b bool) {
+	if !b {
+		log.Fatalf("boom: %v", s)
+	}
+}
+
+var example = []string{
+	"MMMSXXMASM",
+	"MSAMXMSMSA",
+	"AMXSXMAAMM",
+	"MSAMASMSMX",
+	"XMASAMXAMM",
+	"XXAMMXXAMA",
+	"SMSMSASXSS",
+	"SAXAMASAAA",
+	"MAMMMXMMMM",
+	"MXMXAXMASX",
+}
+
+var letterColours = map[rune]render.Colour{
+	'X': render.Red,
+	'M': render.Yellow,
+	'A': render.Green,
+	'S': render.Cyan,
+}
+
+// highlight colours the letters of show, blanking out everything else.
+func highlight(g *grid.Grid[rune], show string) string {
+	t := render.Terminal{Highlight: map[rune]render.Colour{}, Others: '.'}
+	for _, r := range show {
+		t.Highlight[r] = letterColours[r]
+	}
+	return fmt.Sprintf("width:%d height:%d\n%s", g.W, g.H, t.Render(g))
+}
+
+func sameGrid(a, b *grid.Grid[rune]) bool {
+	return a.String() == b.String()
+}
+
+// runTransformTests checks that each transform of a non-square grid comes
+// back to where it started.
+func runTransformTests() {
+	g, err := grid.FromLines([]string{"abcd", "efgh", "ijkl"}, false /*=wantSquare*/)
+	assert(fmt.Sprintf("parse err %v", err), err == nil)
+	v := g.View()
+
+	for _, tc := range []struct {
+		name string
+		got  *grid.View[rune]
+	}{
+		{"identity", v},
+		{"rot90 x4", v.Rotate90().Rotate90().Rotate90().Rotate90()},
+		{"rot90 rot270", v.Rotate90().Rotate270()},
+		{"rot270 rot90", v.Rotate270().Rotate90()},
+		{"rot180 x2", v.Rotate180().Rotate180()},
+		{"rot90 x2 rot180", v.Rotate90().Rotate90().Rotate180()},
+		{"flipH x2", v.FlipH().FlipH()},
+		{"flipV x2", v.FlipV().FlipV()},
+		{"flipH flipV rot180", v.FlipH().FlipV().Rotate180()},
+		{"transpose x2", v.Transpose().Transpose()},
+		{"transpose flipH rot270", v.Transpose().FlipH().Rotate270()},
+	} {
+		assert(fmt.Sprintf("%s: got\n%v", tc.name, tc.got.Grid()), sameGrid(tc.got.Grid(), g))
+	}
+
+	for _, tc := range []struct {
+		name string
+		got  *grid.View[rune]
+		want []string
+	}{
+		{"rot90", v.Rotate90(), []string{"iea", "jfb", "kgc", "lhd"}},
+		{"rot180", v.Rotate180(), []string{"lkji", "hgfe", "dcba"}},
+		{"rot270", v.Rotate270(), []string{"dhl", "cgk", "bfj", "aei"}},
+		{"flipH", v.FlipH(), []string{"dcba", "hgfe", "lkji"}},
+		{"flipV", v.FlipV(), []string{"ijkl", "efgh", "abcd"}},
+		{"transpose", v.Transpose(), []string{"aei", "bfj", "cgk", "dhl"}},
+	} {
+		want, _ := grid.FromLines(tc.want, false /*=wantSquare*/)
+		assert(fmt.Sprintf("%s: got\n%v", tc.name, tc.got.Grid()), sameGrid(tc.got.Grid(), want))
+	}
+
+	// Windows share cells with the grid, so changes show through.
+	w, err := v.Rotate180().Window(grid.Point{X: 1, Y: 1}, 2, 2)
+	assert(fmt.Sprintf("window err %v", err), err == nil)
+	want, _ := grid.FromLines([]string{"gf", "cb"}, false /*=wantSquare*/)
+	assert(fmt.Sprintf("window: got\n%v", w.Grid()), sameGrid(w.Grid(), want))
+	g.Cells[0][1] = 'B'
+	r, _ := w.At(grid.Point{X: 1, Y: 1})
+	assert(fmt.Sprintf("window didn't see change, got %c", r), r == 'B')
+	g.Cells[0][1] = 'b'
+	full, _ := v.Window(grid.Point{}, g.W, g.H)
+	assert("full window", sameGrid(full.Grid(), g))
+	_, err = v.Window(grid.Point{X: 3, Y: 0}, 2, 1)
+	assert("window off the edge should fail", err != nil)
+
+	var diags []string
+	for _, d := range v.Diagonals() {
+		diags = append(diags, string(v.Values(d)))
+	}
+	got, wantDiags := fmt.Sprint(diags), fmt.Sprint([]string{"i", "ej", "afk", "bgl", "ch", "d"})
+	assert(fmt.Sprintf("diagonals: got %v want %v", got, wantDiags), got == wantDiags)
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
-	"os"
 	"strings"
+
+	"github.com/phad/advent-of-code-2024/lib/grid"
+	"github.com/phad/advent-of-code-2024/lib/render"
 )
 
 type entity rune
@@ -41,6 +44,8 @@ type arena struct {
 	w, h     int
 	entities [][]entity
 	g        guard
+	// onFrame, if set, is shown the arena after every step.
+	onFrame func(*grid.Grid[rune])
 }
 
 func initArena(in []string) (*arena, error) {
@@ -82,6 +87,17 @@ func (a *arena) String() string {
 	return s
 }
 
+// frame copies the arena into a grid for the renderers.
+func (a *arena) frame() *grid.Grid[rune] {
+	g := grid.New(a.w, a.h, rune(empty))
+	for y, row := range a.entities {
+		for x, e := range row {
+			g.Cells[y][x] = rune(e)
+		}
+	}
+	return g
+}
+
 func (a *arena) step() (int, bool) {
 	next := guard{x: a.g.x, y: a.g.y, dir: a.g.dir}
 	a.entities[a.g.y][a.g.x] = visited
@@ -107,6 +123,10 @@ func (a *arena) step() (int, bool) {
 		a.entities[a.g.y][a.g.x] = next.dir
 	}
 
+	if a.onFrame != nil {
+		a.onFrame(a.frame())
+	}
+
 	numVisited := 0
 	for i := 0; i < a.w; i++ {
 		for j := 0; j < a.h; j++ {
@@ -120,10 +140,12 @@ func (a *arena) step() (int, bool) {
 
 func main() {
 	log.Println("AoC-2024-day06-part1")
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: main <in file>")
+	rec := render.RecorderFlags(palette)
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: main [-png out.png] [-gif out.gif] <in file>")
 	}
-	lines, err := readLines(os.Args[1])
+	lines, err := readLines(flag.Arg(0))
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
@@ -132,6 +154,10 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
+	if rec.Enabled() {
+		a.onFrame = rec.Frame
+		a.onFrame(a.frame())
+	}
 
 	numVisited := 0
 	lastState := a.String()
@@ -148,5 +174,8 @@ func main() {
 			break
 		}
 	}
+	if err := rec.Close(); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
 	log.Printf("Guard visited %d locations", numVisited)
 }
@@ -2,9 +2,12 @@ package main
 
 import (
 	"bufio"
+	"image/color"
 	"log"
 	"os"
 	"strconv"
+
+	"github.com/phad/advent-of-code-2024/lib/render"
 )
 
 func readLines(f string) ([]string, error) {
@@ -30,3 +33,17 @@ func mustParseInt(s string) int64 {
 	}
 	return v
 }
+
+var guardColour = color.RGBA{0xff, 0x30, 0x30, 0xff}
+
+var palette = render.Palette[rune]{
+	Colours: map[rune]color.Color{
+		'#': color.RGBA{0x80, 0x80, 0x80, 0xff},
+		'X': color.RGBA{0x30, 0x60, 0xc0, 0xff},
+		'^': guardColour,
+		'>': guardColour,
+		'v': guardColour,
+		'<': guardColour,
+	},
+	Default: color.Black,
+}
@@ -1,227 +1,210 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
-	"os"
+	"math/rand"
 	"strings"
-)
+	"testing"
 
-type grid struct {
-	w, h  int
-	cells [][]int
-}
+	"github.com/phad/advent-of-code-2024/lib/render"
+	"github.com/phad/advent-of-code-2024/lib/search"
+)
 
-func newGrid(in []string) (*grid, error) {
-	g := &grid{h: len(in)}
-	for i, r := range in {
-		if i == 0 {
-			g.w = len(r)
-			if g.w != g.h {
-				return nil, fmt.Errorf("Grid isn't square: width %d != height %d", g.w, g.h)
+/* Example input: see the example file.
+
+Sum the scores of all trailheads: how many 9s each can reach.
+*/
+
+func runTests() {
+	for _, tc := range []struct {
+		name  string
+		lines []string
+		want  int
+	}{
+		{"example", example, 36},
+		{"one peak", []string{"0123", "1234", "8765", "9876"}, 1},
+		{"forked", []string{
+			"...0...",
+			"...1...",
+			"...2...",
+			"6543456",
+			"7.....7",
+			"8.....8",
+			"9.....9",
+		}, 2},
+	} {
+		g, err := newGrid(tc.lines)
+		assert(fmt.Sprintf("%s: newGrid err %v", tc.name, err), err == nil)
+		t := newTrails(g)
+		score, _ := t.totals()
+		assert(fmt.Sprintf("%s: score %d want %d", tc.name, score, tc.want), score == tc.want)
+		err = enumerate(t, tc.lines, nil, false)
+		assert(fmt.Sprintf("%s: %v", tc.name, err), err == nil)
+
+		// Looked at from the peaks, the totals must come out the same.
+		score, rating := t.totals()
+		peakScores, peakRatings := t.peakStats()
+		sum := func(m map[point]int) int {
+			n := 0
+			for _, v := range m {
+				n += v
 			}
+			return n
 		}
-		if i > 0 && len(r) != g.w {
-			return nil, fmt.Errorf("Row %d wrong size %d want %d", 1, len(r), g.w)
-		}
-		var row []int
-		for j := 0; j < g.w; j++ {
-			row = append(row, int(mustParseInt(r[j:j+1])))
-		}
-		g.cells = append(g.cells, row)
-	}
-	return g, nil
-}
+		assert(fmt.Sprintf("%s: peak scores sum to %d want %d", tc.name, sum(peakScores), score), sum(peakScores) == score)
+		assert(fmt.Sprintf("%s: peak ratings sum to %d want %d", tc.name, sum(peakRatings), rating), sum(peakRatings) == rating)
 
-func (g *grid) String() string {
-	var s strings.Builder
-	s.WriteString(fmt.Sprintf("width:%d height:%d\n", g.w, g.h))
-	for _, r := range g.cells {
-		for _, c := range r {
-			s.WriteString(fmt.Sprintf("%d", c))
+		searched := 0
+		for _, th := range g.findTrailheads() {
+			searched += t.searchScore(th.start)
 		}
-		s.WriteRune('\n')
-	}
-	return s.String()
-}
-
-type point struct{ x, y int }
-
-func (p point) String() string {
-	return fmt.Sprintf("(%d,%d)", p.x, p.y)
-}
-
-func (g *grid) heightAt(p point) int {
-	if p.x < 0 || p.x >= g.w || p.y < 0 || p.y >= g.h {
-		log.Fatalf("Point %v is outside the grid!", p)
-	}
-	return g.cells[p.y][p.x]
-}
-
-type route []point
-
-type trailhead struct {
-	start  point
-	routes []route
-}
-
-func (th trailhead) score() int {
-	m := map[point]int{}
-	for _, r := range th.routes {
-		m[r[len(r)-1]]++
-	}
-	return len(m)
-}
-
-func (g *grid) findTrailheads() []*trailhead {
-	var ths []*trailhead
-	for y, r := range g.cells {
-		for x, c := range r {
-			if c == 0 {
-				ths = append(ths, &trailhead{start: point{x, y}})
+		assert(fmt.Sprintf("%s: searched score %d want %d", tc.name, searched, score), searched == score)
+		reached := 0
+		for _, n := range peakScores {
+			if n > 0 {
+				reached++
 			}
 		}
+		peaks := t.reachablePeaks()
+		assert(fmt.Sprintf("%s: %d peaks reachable want %d", tc.name, len(peaks), reached), len(peaks) == reached)
 	}
-	return ths
-}
-
-type dir int
-
-const (
-	up    = 0
-	right = 1
-	down  = 2
-	left  = 3
-)
-
-func (d dir) String() string {
-	switch d {
-	case up:
-		return "up"
-	case right:
-		return "right"
-	case down:
-		return "down"
-	case left:
-		return "left"
-	}
-	return "unknowndir"
-}
 
-type state struct {
-	level   int
-	todo    []dir
-	visited route
+	runExportTests()
 }
 
-type routeFinder struct {
-	g      *grid
-	states []*state
+func runExportTests() {
+	g, err := newGrid([]string{"0123", "1234", "8765", "9876"})
+	assert(fmt.Sprintf("newGrid err %v", err), err == nil)
+	t := newTrails(g)
+
+	var dot strings.Builder
+	err = writeDOT(&dot, t)
+	assert(fmt.Sprintf("writeDOT err %v", err), err == nil)
+	for _, want := range []string{
+		`"0,0" [label="0\n(0,0)\nscore 1 rating 16" shape=doublecircle`,
+		`"0,3" [label="9\n(0,3)\nscore 1 rating 16" shape=doublecircle`,
+		`"1,2" [label="7\n(1,2)"];`,
+		`"0,0" -> "1,0";`,
+		`"0,0" -> "0,1";`,
+	} {
+		assert(fmt.Sprintf("DOT missing %s:\n%s", want, dot.String()), strings.Contains(dot.String(), want))
+	}
+
+	var js strings.Builder
+	err = writeJSON(&js, t)
+	assert(fmt.Sprintf("writeJSON err %v", err), err == nil)
+	var got jsonGraph
+	err = json.Unmarshal([]byte(js.String()), &got)
+	assert(fmt.Sprintf("JSON doesn't parse: %v", err), err == nil)
+	_, edges := t.graph()
+	assert(fmt.Sprintf("JSON has %d nodes %d edges want 16, %d", len(got.Nodes), len(got.Edges), len(edges)), len(got.Nodes) == 16 && len(got.Edges) == len(edges))
+	th := got.Nodes[0]
+	assert(fmt.Sprintf("JSON trailhead %+v", th), th.Kind == "trailhead" && *th.Score == 1 && *th.Rating == 16)
+	assert(fmt.Sprintf("JSON node %+v should have no score", got.Nodes[1]), got.Nodes[1].Score == nil)
 }
 
-func newRouteFinder(g *grid) *routeFinder {
-	return &routeFinder{g: g}
-}
-
-func (rf *routeFinder) addRoutesFor(th *trailhead) {
-	//log.Printf("Analysing trailhead at %v height %d", th.start, rf.g.heightAt(th.start))
-	// Iniialise search, retaining current and previous states in a stack.
-	pos := th.start
-	st := &state{
-		level:   rf.g.heightAt(pos),
-		visited: []point{pos},
+var bench = flag.Bool("bench", false, "benchmark scoring on generated maps instead of solving")
+
+// randomMap makes an n*n map whose heights mostly climb diagonally, so
+// that there are plenty of long trails, with some noise to branch them.
+func randomMap(n int, seed int64) *grid {
+	r := rand.New(rand.NewSource(seed))
+	g := &grid{w: n, h: n}
+	for y := 0; y < n; y++ {
+		row := make([]int, n)
+		for x := range row {
+			row[x] = (x + y) % 10
+			if r.Intn(5) == 0 {
+				row[x] = r.Intn(10)
+			}
+		}
+		g.cells = append(g.cells, row)
 	}
-	rf.states = append(rf.states, st)
-
-	// Start visit of a new position
-	rf.iterate(pos, func(st *state) {
-		th.routes = append(th.routes, st.visited)
-	})
+	return g
 }
 
-func (rf *routeFinder) iterate(pos point, onRouteDone func(st *state)) {
-	if len(rf.states) == 0 {
-		log.Fatalf("Can't iterate when state stack is empty!")
-	}
-	// Are we at the max height of 9? If so, report this route.
-	st := rf.states[len(rf.states)-1]
-	if rf.g.heightAt(pos) == 9 {
-		//log.Printf("Completed route at %s height 9", pos)
-		onRouteDone(st)
-		return
-	}
-
-	if pos.x > 0 {
-		st.todo = append(st.todo, left)
-	}
-	if pos.x < rf.g.w-1 {
-		st.todo = append(st.todo, right)
-	}
-	if pos.y > 0 {
-		st.todo = append(st.todo, up)
-	}
-	if pos.y < rf.g.h-1 {
-		st.todo = append(st.todo, down)
-	}
-	//log.Printf("From %v can go %v", pos, st.todo)
-
-	// Iterate todo list.
-	for _, dir := range st.todo {
-		var next point
-		switch dir {
-		case up:
-			next = point{x: pos.x, y: pos.y - 1}
-		case right:
-			next = point{x: pos.x + 1, y: pos.y}
-		case down:
-			next = point{x: pos.x, y: pos.y + 1}
-		case left:
-			next = point{x: pos.x - 1, y: pos.y}
-		}
-		// Can only move to a location with height 1 greater than current height.
-		if rf.g.heightAt(next) != st.level+1 {
-			//log.Printf("Not going to %v because it's wrong height %d want %d", next, rf.g.heightAt(next), st.level+1)
-			continue
+func runBenchmarks() {
+	for _, n := range []int{60, 250} {
+		g := randomMap(n, 1)
+		t := newTrails(g)
+		ths := g.findTrailheads()
+		log.Printf("%dx%d map, %d trailheads, %d peaks:", n, n, len(ths), len(t.peaks))
+		for _, b := range []struct {
+			name string
+			f    func()
+		}{
+			{"newTrails (DP)", func() { newTrails(g).totals() }},
+			{"SolveTargets per trailhead", func() {
+				for _, th := range ths {
+					t.searchScore(th.start)
+				}
+			}},
+			{"reachable: per trailhead", func() {
+				reached := map[point]bool{}
+				for _, th := range ths {
+					p := g.problem()
+					p.Start = th.start
+					for pk := range search.SolveTargets(search.BFS, p, t.peaks).Costs {
+						reached[pk] = true
+					}
+				}
+			}},
+			{"reachable: multi-source", func() { t.reachablePeaks() }},
+		} {
+			res := testing.Benchmark(func(tb *testing.B) {
+				for i := 0; i < tb.N; i++ {
+					b.f()
+				}
+			})
+			log.Printf("  %-28s %v", b.name, res)
 		}
-		// This height looks good. Stack new state and iterate.
-		//log.Printf("Trying move from %v height %d to %v height %d", pos, st.level, next, st.level+1)
-		nextSt := &state{
-			level:   st.level + 1,
-			visited: make([]point, len(st.visited)),
-		}
-		copy(nextSt.visited, st.visited)
-		nextSt.visited = append(nextSt.visited, next)
-		rf.states = append(rf.states, nextSt)
-		rf.iterate(next, onRouteDone)
 	}
 }
 
 func main() {
 	log.Println("AoC-2024-day10-part1")
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: main <in file>")
+	walk := flag.Bool("enumerate", false, "also walk every route one by one, and check they agree (slow)")
+	ex := render.ExplorerFlags()
+	xf := registerExportFlags()
+	flag.Parse()
+	if *bench {
+		runBenchmarks()
+		return
+	}
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: main [-bench] [-enumerate] [-explore] [-explore-png file] [-explore-gif file] [-export dot|json] [-export-out file] <in file>")
 	}
-	lines, err := readLines(os.Args[1])
+	if err := xf.check(); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	lines, err := readLines(flag.Arg(0))
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
 
+	runTests()
+
 	g, err := newGrid(lines)
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
 	log.Printf("Grid:\n%v", g)
 
-	ths := g.findTrailheads()
-	rf := newRouteFinder(g)
-	score := 0
-	for i, th := range ths {
-		rf.addRoutesFor(th)
-		log.Printf("Trailhead %d has %d routes (%d unique endpoints == score)", i, len(th.routes), th.score())
-		score += th.score()
-		/*for j, r := range th.routes {
-			log.Printf(" - Route %d: %v", j, r)
-		}*/
+	t := newTrails(g)
+	// Drawing needs the routes, so walks them too.
+	if *walk || ex.Enabled() {
+		if err := enumerate(t, lines, ex, true); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+	score, _ := t.totals()
+	if xf.enabled() {
+		if err := xf.write(t); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
 	}
 	log.Printf("Overall score: %v", score)
 }
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// The trail map as a graph: every passable cell is a node, with an edge to
+// each neighbour exactly one higher. Trailheads are annotated with their
+// score and rating. Peaks are too, from the other end: the score is how
+// many trailheads reach it, and the rating how many routes end there.
+
+type exportFlags struct {
+	format *string
+	out    *string
+}
+
+func registerExportFlags() *exportFlags {
+	return &exportFlags{
+		format: flag.String("export", "", "write the trail graph out as dot or json"),
+		out:    flag.String("export-out", "", "file to write the trail graph to (default stdout)"),
+	}
+}
+
+func (f *exportFlags) enabled() bool {
+	return *f.format != ""
+}
+
+// check validates the flags, so that a typo fails before any work is done.
+func (f *exportFlags) check() error {
+	switch *f.format {
+	case "", "dot", "json":
+		return nil
+	}
+	return fmt.Errorf("unknown -export format %q, want dot or json", *f.format)
+}
+
+func (f *exportFlags) write(t *trails) error {
+	var w io.Writer = os.Stdout
+	if *f.out != "" {
+		file, err := os.Create(*f.out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	var err error
+	switch *f.format {
+	case "dot":
+		err = writeDOT(w, t)
+	case "json":
+		err = writeJSON(w, t)
+	default:
+		err = f.check()
+	}
+	if err == nil && *f.out != "" {
+		log.Printf("Wrote trail graph to %s", *f.out)
+	}
+	return err
+}
+
+type node struct {
+	p             point
+	height        int
+	score, rating int
+	trailhead     bool
+	peak          bool
+}
+
+// graph lists the nodes, in reading order, and the edges between them.
+func (t *trails) graph() ([]node, [][2]point) {
+	peakScores, peakRatings := t.peakStats()
+	var nodes []node
+	var edges [][2]point
+	for y, r := range t.g.cells {
+		for x, h := range r {
+			if h == impassable {
+				continue
+			}
+			n := node{p: point{x, y}, height: h}
+			switch h {
+			case 0:
+				n.trailhead, n.score, n.rating = true, t.score(n.p), t.rating(n.p)
+			case 9:
+				n.peak, n.score, n.rating = true, peakScores[n.p], peakRatings[n.p]
+			}
+			nodes = append(nodes, n)
+			for _, up := range t.uphill(n.p) {
+				edges = append(edges, [2]point{n.p, up})
+			}
+		}
+	}
+	return nodes, edges
+}
+
+func nodeID(p point) string {
+	return fmt.Sprintf("%d,%d", p.x, p.y)
+}
+
+func writeDOT(w io.Writer, t *trails) error {
+	nodes, edges := t.graph()
+	var s strings.Builder
+	s.WriteString("digraph trails {\n")
+	s.WriteString("\tnode [shape=circle];\n")
+	for _, n := range nodes {
+		attrs := fmt.Sprintf("label=\"%d\\n%v\"", n.height, n.p)
+		switch {
+		case n.trailhead:
+			attrs = fmt.Sprintf("label=\"0\\n%v\\nscore %d rating %d\" shape=doublecircle style=filled fillcolor=lightblue", n.p, n.score, n.rating)
+		case n.peak:
+			attrs = fmt.Sprintf("label=\"9\\n%v\\nscore %d rating %d\" shape=doublecircle style=filled fillcolor=salmon", n.p, n.score, n.rating)
+		}
+		s.WriteString(fmt.Sprintf("\t%q [%s];\n", nodeID(n.p), attrs))
+	}
+	for _, e := range edges {
+		s.WriteString(fmt.Sprintf("\t%q -> %q;\n", nodeID(e[0]), nodeID(e[1])))
+	}
+	s.WriteString("}\n")
+	_, err := io.WriteString(w, s.String())
+	return err
+}
+
+type jsonNode struct {
+	ID     string `json:"id"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Height int    `json:"height"`
+	// Kind is "trailhead", "peak" or empty; only those have a score and
+	// rating.
+	Kind   string `json:"kind,omitempty"`
+	Score  *int   `json:"score,omitempty"`
+	Rating *int   `json:"rating,omitempty"`
+}
+
+type jsonEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type jsonGraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+}
+
+func writeJSON(w io.Writer, t *trails) error {
+	nodes, edges := t.graph()
+	g := jsonGraph{Nodes: []jsonNode{}, Edges: []jsonEdge{}}
+	for _, n := range nodes {
+		jn := jsonNode{ID: nodeID(n.p), X: n.p.x, Y: n.p.y, Height: n.height}
+		if n.trailhead || n.peak {
+			jn.Kind = "trailhead"
+			if n.peak {
+				jn.Kind = "peak"
+			}
+			score, rating := n.score, n.rating
+			jn.Score, jn.Rating = &score, &rating
+		}
+		g.Nodes = append(g.Nodes, jn)
+	}
+	for _, e := range edges {
+		g.Edges = append(g.Edges, jsonEdge{From: nodeID(e[0]), To: nodeID(e[1])})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
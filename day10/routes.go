@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/phad/advent-of-code-2024/lib/render"
+	"github.com/phad/advent-of-code-2024/lib/search"
+)
+
+// Walking every route one at a time is how this day was first solved. It's
+// far slower than trails, but is kept as a cross-check and for drawing.
+
+type route []point
+
+type trailhead struct {
+	start  point
+	routes []route
+}
+
+func (th trailhead) score() int {
+	m := map[point]int{}
+	for _, r := range th.routes {
+		m[r[len(r)-1]]++
+	}
+	return len(m)
+}
+
+func (g *grid) findTrailheads() []*trailhead {
+	var ths []*trailhead
+	for y, r := range g.cells {
+		for x, c := range r {
+			if c == 0 {
+				ths = append(ths, &trailhead{start: point{x, y}})
+			}
+		}
+	}
+	return ths
+}
+
+type dir int
+
+const (
+	up    = 0
+	right = 1
+	down  = 2
+	left  = 3
+)
+
+func (d dir) String() string {
+	switch d {
+	case up:
+		return "up"
+	case right:
+		return "right"
+	case down:
+		return "down"
+	case left:
+		return "left"
+	}
+	return "unknowndir"
+}
+
+type state struct {
+	level   int
+	todo    []dir
+	visited route
+}
+
+type routeFinder struct {
+	g      *grid
+	states []*state
+	// observe, if set, is called on every position visited.
+	observe func(pos point, level int)
+}
+
+func newRouteFinder(g *grid) *routeFinder {
+	return &routeFinder{g: g}
+}
+
+func (rf *routeFinder) addRoutesFor(th *trailhead) {
+	//log.Printf("Analysing trailhead at %v height %d", th.start, rf.g.heightAt(th.start))
+	// Iniialise search, retaining current and previous states in a stack.
+	pos := th.start
+	st := &state{
+		level:   rf.g.heightAt(pos),
+		visited: []point{pos},
+	}
+	rf.states = append(rf.states, st)
+
+	// Start visit of a new position
+	rf.iterate(pos, func(st *state) {
+		th.routes = append(th.routes, st.visited)
+	})
+}
+
+func (rf *routeFinder) iterate(pos point, onRouteDone func(st *state)) {
+	if len(rf.states) == 0 {
+		log.Fatalf("Can't iterate when state stack is empty!")
+	}
+	// Are we at the max height of 9? If so, report this route.
+	st := rf.states[len(rf.states)-1]
+	if rf.observe != nil {
+		rf.observe(pos, st.level)
+	}
+	if rf.g.heightAt(pos) == 9 {
+		//log.Printf("Completed route at %s height 9", pos)
+		onRouteDone(st)
+		return
+	}
+
+	if pos.x > 0 {
+		st.todo = append(st.todo, left)
+	}
+	if pos.x < rf.g.w-1 {
+		st.todo = append(st.todo, right)
+	}
+	if pos.y > 0 {
+		st.todo = append(st.todo, up)
+	}
+	if pos.y < rf.g.h-1 {
+		st.todo = append(st.todo, down)
+	}
+	//log.Printf("From %v can go %v", pos, st.todo)
+
+	// Iterate todo list.
+	for _, dir := range st.todo {
+		var next point
+		switch dir {
+		case up:
+			next = point{x: pos.x, y: pos.y - 1}
+		case right:
+			next = point{x: pos.x + 1, y: pos.y}
+		case down:
+			next = point{x: pos.x, y: pos.y + 1}
+		case left:
+			next = point{x: pos.x - 1, y: pos.y}
+		}
+		// Can only move to a location with height 1 greater than current height.
+		if rf.g.heightAt(next) != st.level+1 {
+			//log.Printf("Not going to %v because it's wrong height %d want %d", next, rf.g.heightAt(next), st.level+1)
+			continue
+		}
+		// This height looks good. Stack new state and iterate.
+		//log.Printf("Trying move from %v height %d to %v height %d", pos, st.level, next, st.level+1)
+		nextSt := &state{
+			level:   st.level + 1,
+			visited: make([]point, len(st.visited)),
+		}
+		copy(nextSt.visited, st.visited)
+		nextSt.visited = append(nextSt.visited, next)
+		rf.states = append(rf.states, nextSt)
+		rf.iterate(next, onRouteDone)
+	}
+}
+
+// enumerate walks every route from every trailhead and checks that they
+// agree with t, logging each trailhead if verbose. If ex asked for any
+// drawings, the walk and its routes are drawn.
+func enumerate(t *trails, lines []string, ex *render.Explorer, verbose bool) error {
+	rf := newRouteFinder(t.g)
+	var rec *search.Recording[point]
+	if ex != nil && ex.Enabled() {
+		rec = &search.Recording[point]{Every: ex.Every()}
+		rf.observe = func(pos point, level int) { rec.Observe(pos, level, nil) }
+	}
+	for i, th := range t.g.findTrailheads() {
+		rf.addRoutesFor(th)
+		if verbose {
+			log.Printf("Trailhead %d at %v has %d routes (%d unique endpoints == score)", i, th.start, len(th.routes), th.score())
+		}
+		if th.score() != t.score(th.start) || len(th.routes) != t.rating(th.start) {
+			return fmt.Errorf("trailhead %d at %v: walked score %d rating %d, but worked out score %d rating %d",
+				i, th.start, th.score(), len(th.routes), t.score(th.start), t.rating(th.start))
+		}
+		if rec != nil {
+			for _, r := range th.routes {
+				rec.AddPath(r)
+			}
+		}
+	}
+	if rec == nil {
+		return nil
+	}
+	e, err := exploration(lines, rec)
+	if err != nil {
+		return err
+	}
+	return ex.Write(e)
+}
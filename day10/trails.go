@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/bits"
+	"strings"
+
+	"github.com/phad/advent-of-code-2024/lib/search"
+)
+
+// impassable marks a '.' on the map, which no trail goes through.
+const impassable = -1
+
+type grid struct {
+	w, h  int
+	cells [][]int
+}
+
+func newGrid(in []string) (*grid, error) {
+	g := &grid{h: len(in)}
+	for i, r := range in {
+		if i == 0 {
+			g.w = len(r)
+			if g.w != g.h {
+				return nil, fmt.Errorf("Grid isn't square: width %d != height %d", g.w, g.h)
+			}
+		}
+		if i > 0 && len(r) != g.w {
+			return nil, fmt.Errorf("Row %d wrong size %d want %d", i, len(r), g.w)
+		}
+		var row []int
+		for j := 0; j < g.w; j++ {
+			if r[j] == '.' {
+				row = append(row, impassable)
+				continue
+			}
+			row = append(row, int(mustParseInt(r[j:j+1])))
+		}
+		g.cells = append(g.cells, row)
+	}
+	return g, nil
+}
+
+func (g *grid) String() string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("width:%d height:%d\n", g.w, g.h))
+	for _, r := range g.cells {
+		for _, c := range r {
+			if c == impassable {
+				s.WriteRune('.')
+				continue
+			}
+			s.WriteString(fmt.Sprintf("%d", c))
+		}
+		s.WriteRune('\n')
+	}
+	return s.String()
+}
+
+type point struct{ x, y int }
+
+func (p point) String() string {
+	return fmt.Sprintf("(%d,%d)", p.x, p.y)
+}
+
+func (g *grid) heightAt(p point) int {
+	if p.x < 0 || p.x >= g.w || p.y < 0 || p.y >= g.h {
+		log.Fatalf("Point %v is outside the grid!", p)
+	}
+	return g.cells[p.y][p.x]
+}
+
+// peakSet is a bitset of peaks, indexed by their position in trails.peaks.
+type peakSet []uint64
+
+func newPeakSet(n int) peakSet {
+	return make(peakSet, (n+63)/64)
+}
+
+func (s peakSet) add(i int) {
+	s[i/64] |= 1 << (i % 64)
+}
+
+func (s peakSet) union(o peakSet) {
+	for i := range s {
+		s[i] |= o[i]
+	}
+}
+
+func (s peakSet) has(i int) bool {
+	return s != nil && s[i/64]&(1<<(i%64)) != 0
+}
+
+func (s peakSet) count() int {
+	n := 0
+	for _, w := range s {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// trails holds, for every cell, which 9s can be reached from it and how
+// many distinct routes lead up to them. Both are worked out in one pass
+// down from height 9 to 0: a cell can reach whatever its neighbours one
+// higher can, along the sum of their routes.
+type trails struct {
+	g      *grid
+	peaks  []point
+	reach  [][]peakSet
+	routes [][]int
+}
+
+func newTrails(g *grid) *trails {
+	t := &trails{g: g, reach: make([][]peakSet, g.h), routes: make([][]int, g.h)}
+	byHeight := make([][]point, 10)
+	for y, r := range g.cells {
+		for x, c := range r {
+			if c != impassable {
+				byHeight[c] = append(byHeight[c], point{x, y})
+			}
+		}
+	}
+	t.peaks = byHeight[9]
+	for y := range t.reach {
+		t.reach[y] = make([]peakSet, g.w)
+		t.routes[y] = make([]int, g.w)
+	}
+	for i, p := range t.peaks {
+		t.reach[p.y][p.x] = newPeakSet(len(t.peaks))
+		t.reach[p.y][p.x].add(i)
+		t.routes[p.y][p.x] = 1
+	}
+	for h := 8; h >= 0; h-- {
+		for _, p := range byHeight[h] {
+			reach := newPeakSet(len(t.peaks))
+			routes := 0
+			for _, n := range t.uphill(p) {
+				reach.union(t.reach[n.y][n.x])
+				routes += t.routes[n.y][n.x]
+			}
+			t.reach[p.y][p.x], t.routes[p.y][p.x] = reach, routes
+		}
+	}
+	return t
+}
+
+// steps lists p's neighbours whose height is exactly delta more than p's.
+func (g *grid) steps(p point, delta int) []point {
+	var ns []point
+	for _, n := range []point{{p.x, p.y - 1}, {p.x + 1, p.y}, {p.x, p.y + 1}, {p.x - 1, p.y}} {
+		if n.x >= 0 && n.x < g.w && n.y >= 0 && n.y < g.h && g.cells[n.y][n.x] == g.cells[p.y][p.x]+delta {
+			ns = append(ns, n)
+		}
+	}
+	return ns
+}
+
+// uphill lists where a trail can go next from p.
+func (t *trails) uphill(p point) []point {
+	return t.g.steps(p, 1)
+}
+
+// score is how many 9s can be reached from p.
+func (t *trails) score(p point) int {
+	if t.reach[p.y][p.x] == nil {
+		return 0
+	}
+	return t.reach[p.y][p.x].count()
+}
+
+// rating is how many distinct routes lead from p up to a 9.
+func (t *trails) rating(p point) int {
+	return t.routes[p.y][p.x]
+}
+
+// totals sums score and rating over every trailhead.
+func (t *trails) totals() (score, rating int) {
+	for _, th := range t.g.findTrailheads() {
+		score += t.score(th.start)
+		rating += t.rating(th.start)
+	}
+	return score, rating
+}
+
+// peakStats looks at trails from the top: for each peak, how many
+// trailheads can reach it and how many routes end there. Routes are
+// counted with the same DP as newTrails, but going up from 0 to 9.
+func (t *trails) peakStats() (scores, ratings map[point]int) {
+	scores, ratings = map[point]int{}, map[point]int{}
+	for _, th := range t.g.findTrailheads() {
+		reach := t.reach[th.start.y][th.start.x]
+		for i, p := range t.peaks {
+			if reach.has(i) {
+				scores[p]++
+			}
+		}
+	}
+	from := map[point]int{}
+	for h := 0; h <= 9; h++ {
+		for y, r := range t.g.cells {
+			for x, c := range r {
+				if c != h {
+					continue
+				}
+				p := point{x, y}
+				if h == 0 {
+					from[p] = 1
+					continue
+				}
+				for _, n := range t.g.steps(p, -1) {
+					from[p] += from[n]
+				}
+			}
+		}
+	}
+	for _, p := range t.peaks {
+		ratings[p] = from[p]
+	}
+	return scores, ratings
+}
+
+// problem is the map as a search space, where each move is a step uphill.
+func (g *grid) problem() search.Problem[point] {
+	return search.Problem[point]{
+		Neighbours: func(p point) []search.Edge[point] {
+			var es []search.Edge[point]
+			for _, n := range g.steps(p, 1) {
+				es = append(es, search.Edge[point]{To: n, Cost: 1})
+			}
+			return es
+		},
+	}
+}
+
+// searchScore works out th's score with a search for all the peaks, which
+// stops early if it finds them all. It's a cross-check on the DP in
+// trails, and a benchmark for it.
+func (t *trails) searchScore(th point) int {
+	p := t.g.problem()
+	p.Start = th
+	return len(search.SolveTargets(search.BFS, p, t.peaks).Costs)
+}
+
+// reachablePeaks lists the peaks that can be reached from any trailhead,
+// with one search out from all of them at once.
+func (t *trails) reachablePeaks() []point {
+	p := t.g.problem()
+	for _, th := range t.g.findTrailheads() {
+		p.Starts = append(p.Starts, th.start)
+	}
+	if len(p.Starts) == 0 {
+		return nil
+	}
+	costs := search.SolveTargets(search.BFS, p, t.peaks).Costs
+	var peaks []point
+	for _, pk := range t.peaks {
+		if _, ok := costs[pk]; ok {
+			peaks = append(peaks, pk)
+		}
+	}
+	return peaks
+}
@@ -5,6 +5,10 @@ import (
 	"log"
 	"os"
 	"strconv"
+
+	libgrid "github.com/phad/advent-of-code-2024/lib/grid"
+	"github.com/phad/advent-of-code-2024/lib/render"
+	"github.com/phad/advent-of-code-2024/lib/search"
 )
 
 func readLines(f string) ([]string, error) {
@@ -30,3 +34,30 @@ func mustParseInt(s string) int64 {
 	}
 	return v
 }
+
+// exploration lays the recorded route search over the map. Costs are
+// heights, so cells shade from blue trailheads to red peaks.
+func exploration(lines []string, rec *search.Recording[point]) (*render.Exploration, error) {
+	base, err := libgrid.FromLines(lines, false)
+	if err != nil {
+		return nil, err
+	}
+	return render.NewExploration(base, rec, func(p point) libgrid.Point { return libgrid.Point{X: p.x, Y: p.y} }), nil
+}
+
+func assert(s string, b bool) {
+	if !b {
+		log.Fatalf("boom: %v", s)
+	}
+}
+
+var example = []string{
+	"89010123",
+	"78121874",
+	"87430965",
+	"96549874",
+	"45678903",
+	"32019012",
+	"01329801",
+	"10456732",
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+
+	"github.com/phad/advent-of-code-2024/lib/grid"
+	"github.com/phad/advent-of-code-2024/lib/unionfind"
 )
 
 /* Example input
@@ -13,54 +16,6 @@ BBCC
 EEEC
 */
 
-type grid struct {
-	w, h  int
-	cells [][]rune
-}
-
-func newGrid(in []string) (*grid, error) {
-	g := &grid{h: len(in)}
-	for i, r := range in {
-		if i == 0 {
-			g.w = len(r)
-			if g.w != g.h {
-				return nil, fmt.Errorf("Grid isn't square: width %d != height %d", g.w, g.h)
-			}
-		}
-		if i > 0 && len(r) != g.w {
-			return nil, fmt.Errorf("Row %d wrong size %d want %d", 1, len(r), g.w)
-		}
-		row := []rune(r)
-		g.cells = append(g.cells, row)
-	}
-	return g, nil
-}
-
-func (g *grid) String() string {
-	s := fmt.Sprintf("width:%d height:%d\n", g.w, g.h)
-	for _, r := range g.cells {
-		s += string(r)
-		s += "\n"
-	}
-	return s
-}
-
-func (g *grid) highlight(show rune) string {
-	s := fmt.Sprintf("width:%d height:%d\n", g.w, g.h)
-	for _, row := range g.cells {
-		r := make([]rune, g.w)
-		copy(r, row)
-		for i, c := range row {
-			if show != c {
-				r[i] = '.'
-			}
-		}
-		s += string(r)
-		s += "\n"
-	}
-	return s
-}
-
 type point struct{ x, y int }
 
 type region struct {
@@ -72,106 +27,33 @@ func (r *region) String() string {
 	return fmt.Sprintf("<%s: %v>", string(r.plant), r.cells)
 }
 
-type node struct {
-	cell point
-}
-
-func (n *node) String() string {
-	return fmt.Sprintf("<%v>", n.cell)
-}
-
-func (g *grid) findRegions() []*region {
-	// Start by creating a lot of 1-cell nodes for union-find.
-	cellsByPlant := map[rune][]*node{}
-	for y, row := range g.cells {
+// findRegions is the original union-find approach to regions.  It is only
+// used by runTests now, as a cross-check on grid.Label.
+func findRegions(g *grid.Grid[rune]) []*region {
+	// Every cell starts out as its own set; merge it with its right and
+	// lower neighbours when they grow the same plant.
+	ds := unionfind.New[point]()
+	for y, row := range g.Cells {
 		for x, plant := range row {
-			cs, ok := cellsByPlant[plant]
-			if !ok {
-				cs = []*node{}
-				cellsByPlant[plant] = cs
+			p := point{x, y}
+			ds.Add(p)
+			if x+1 < g.W && row[x+1] == plant {
+				ds.Union(p, point{x + 1, y})
+			}
+			if y+1 < g.H && g.Cells[y+1][x] == plant {
+				ds.Union(p, point{x, y + 1})
 			}
-			n := &node{cell: point{x, y}}
-			cellsByPlant[plant] = append(cellsByPlant[plant], n)
 		}
 	}
 
 	var ret []*region
-
-	for plant, nodes := range cellsByPlant {
-		// Now use union-find to merge cells into regions, where
-		// all cells adjoin on 1 or more sides.  Do this per plant
-		// so that we end up with disjoint regions for a particular
-		// plant.  Initially every node is it's own parent.
-		parents := map[*node]*node{}
-		for _, n := range nodes {
-			parents[n] = n
-		}
-		//log.Printf("Plant %s: initial parents:\n%v", string(plant), parents)
-
-		rootFn := func(n *node) *node {
-			var p, root *node
-			p = parents[n]
-			for {
-				if parents[p] == p {
-					root = p
-					break
-				}
-				p = parents[p]
-			}
-			return root
-		}
-
-		// Union
-		for i := 0; i < len(nodes)-1; i++ {
-			for j := i + 1; j < len(nodes); j++ {
-				n1, n2 := nodes[i], nodes[j]
-				if adjoins(n1.cell, n2.cell) {
-					parents[rootFn(n2)] = rootFn(n1)
-				}
-			}
-		}
-		// Find
-		//log.Printf("Plant %s: union-find parents state:\n%v\n", string(plant), parents)
-
-		// Create output regions - need to map each cluster's root to the new region.
-		// 1x1 islands don't have a root in the parents list.
-		regions := map[*node]*region{}
-		for _, n := range nodes {
-			root := rootFn(n)
-			//log.Printf("For node %v found root %v", n, root)
-			reg, ok := regions[root]
-			if !ok {
-				reg = &region{plant: plant}
-				regions[root] = reg
-			}
-			//log.Printf("For root %v found region %v", root, reg)
-			reg.cells = append(reg.cells, n.cell)
-		}
-		//log.Printf("Made regions:\n%v", regions)
-		for _, r := range regions {
-			ret = append(ret, r)
-		}
+	for _, cells := range ds.Components() {
+		first := cells[0]
+		ret = append(ret, &region{plant: g.Cells[first.y][first.x], cells: cells})
 	}
 	return ret
 }
 
-func abs(a int) int {
-	if a >= 0 {
-		return a
-	}
-	return -a
-}
-
-func adjoins(r1, r2 point) bool {
-	if r1.x == r2.x {
-		return abs(r2.y-r1.y) == 1
-	}
-	if r1.y == r2.y {
-		return abs(r2.x-r1.x) == 1
-	}
-	return false
-}
-
 func (r *region) area() int {
 	return len(r.cells)
 }
@@ -211,6 +93,57 @@ func (r *region) perimeter() int {
 	return singlePanels
 }
 
+func totalCost(g *grid.Grid[rune]) int {
+	total := 0
+	for _, c := range grid.Label(g, grid.Equal[rune]).Components {
+		total += c.Area() * c.Perimeter
+	}
+	return total
+}
+
+func legacyTotalCost(g *grid.Grid[rune]) int {
+	total := 0
+	for _, reg := range findRegions(g) {
+		total += reg.area() * reg.perimeter()
+	}
+	return total
+}
+
+func runTests(input *grid.Grid[rune]) {
+	for _, tc := range []struct {
+		lines []string
+		want  int
+	}{
+		{[]string{"A"}, 4},
+		{[]string{"AAAA", "BBCD", "BBCC", "EEEC"}, 140},
+		{[]string{"OOOOO", "OXOXO", "OOOOO", "OXOXO", "OOOOO"}, 772},
+		{[]string{"AB", "BA"}, 16},
+		{[]string{"EEEEE", "EXXXX", "EEEEE", "EXXXX", "EEEEE"}, 692},
+		{[]string{"AAAAAA", "AAABBA", "AAABBA", "ABBAAA", "ABBAAA", "AAAAAA"}, 1184},
+	} {
+		g, err := grid.FromLines(tc.lines, true /*=wantSquare*/)
+		assert(fmt.Sprintf("parse %v err %v", tc.lines, err), err == nil)
+		got := totalCost(g)
+		assert(fmt.Sprintf("%v: cost %d want %d", tc.lines, got, tc.want), got == tc.want)
+		legacy := legacyTotalCost(g)
+		assert(fmt.Sprintf("%v: legacy cost %d want %d", tc.lines, legacy, tc.want), legacy == tc.want)
+	}
+	got, want := totalCost(input), legacyTotalCost(input)
+	assert(fmt.Sprintf("input: cost %d, legacy cost %d", got, want), got == want)
+
+	// Same and Size only look, so don't add keys they haven't seen.
+	ds := unionfind.New[point]()
+	ds.Union(point{0, 0}, point{0, 1})
+	assert("Same of joined keys should be true", ds.Same(point{0, 0}, point{0, 1}))
+	assert("Same with an unknown key should be false", !ds.Same(point{0, 0}, point{5, 5}))
+	assert("Same of an unknown key with itself should be false", !ds.Same(point{5, 5}, point{5, 5}))
+	assert(fmt.Sprintf("Size of a joined key is %d want 2", ds.Size(point{0, 1})), ds.Size(point{0, 1}) == 2)
+	assert(fmt.Sprintf("Size of an unknown key is %d want 0", ds.Size(point{6, 6})), ds.Size(point{6, 6}) == 0)
+	assert(fmt.Sprintf("after looking: Len %d Count %d want 2 1", ds.Len(), ds.Count()), ds.Len() == 2 && ds.Count() == 1)
+	ds.Find(point{7, 7})
+	assert(fmt.Sprintf("after Find: Len %d Count %d want 3 2", ds.Len(), ds.Count()), ds.Len() == 3 && ds.Count() == 2)
+}
+
 func main() {
 	log.Println("AoC-2024-day12-part1")
 	if len(os.Args) < 2 {
@@ -221,19 +154,22 @@ func main() {
 		log.Fatalf("Error: %v", err)
 	}
 
-	g, err := newGrid(lines)
+	g, err := grid.FromLines(lines, true /*=wantSquare*/)
 	if err != nil {
 		log.Fatalf("Failed to parse input: %v", err)
 	}
 	log.Printf("AllPlants:\n%v\n", g)
 
+	runTests(g)
+
+	l := grid.Label(g, grid.Equal[rune])
 	totalCost := 0
-	for _, reg := range g.findRegions() {
-		area := reg.area()
-		perim := reg.perimeter()
+	for _, c := range l.Components {
+		area := c.Area()
+		perim := c.Perimeter
 		cost := area * perim
 		totalCost += cost
-		log.Printf("Plant %s:\n%vArea: %d\nPerimeter: %d\nCost: %d\n\n", string(reg.plant), g.highlight(reg.plant), area, perim, cost)
+		log.Printf("Plant %s:\n%vArea: %d\nPerimeter: %d\nCost: %d\n\n", string(plantOf(g, c)), highlight(g, l, c), area, perim, cost)
 	}
 	log.Printf("Total cost: %d", totalCost)
 }
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
-	"os"
+	"math/rand"
+	"testing"
+
+	"github.com/phad/advent-of-code-2024/lib/grid"
+	"github.com/phad/advent-of-code-2024/lib/unionfind"
 )
 
 /* Example input
@@ -13,54 +18,6 @@ BBCC
 EEEC
 */
 
-type grid struct {
-	w, h  int
-	cells [][]rune
-}
-
-func newGrid(in []string) (*grid, error) {
-	g := &grid{h: len(in)}
-	for i, r := range in {
-		if i == 0 {
-			g.w = len(r)
-			if g.w != g.h {
-				return nil, fmt.Errorf("Grid isn't square: width %d != height %d", g.w, g.h)
-			}
-		}
-		if i > 0 && len(r) != g.w {
-			return nil, fmt.Errorf("Row %d wrong size %d want %d", 1, len(r), g.w)
-		}
-		row := []rune(r)
-		g.cells = append(g.cells, row)
-	}
-	return g, nil
-}
-
-func (g *grid) String() string {
-	s := fmt.Sprintf("width:%d height:%d\n", g.w, g.h)
-	for _, r := range g.cells {
-		s += string(r)
-		s += "\n"
-	}
-	return s
-}
-
-func (g *grid) highlight(show rune) string {
-	s := fmt.Sprintf("width:%d height:%d\n", g.w, g.h)
-	for _, row := range g.cells {
-		r := make([]rune, g.w)
-		copy(r, row)
-		for i, c := range row {
-			if show != c {
-				r[i] = '.'
-			}
-		}
-		s += string(r)
-		s += "\n"
-	}
-	return s
-}
-
 type point struct{ x, y int }
 
 func (p point) String() string {
@@ -76,106 +33,33 @@ func (r *region) String() string {
 	return fmt.Sprintf("<%s: %v>", string(r.plant), r.cells)
 }
 
-type node struct {
-	cell point
-}
-
-func (n *node) String() string {
-	return fmt.Sprintf("<%v>", n.cell)
-}
-
-func (g *grid) findRegions() []*region {
-	// Start by creating a lot of 1-cell nodes for union-find.
-	cellsByPlant := map[rune][]*node{}
-	for y, row := range g.cells {
+// findRegions is the original union-find approach to regions.  It is only
+// used by runTests now, as a cross-check on grid.Label.
+func findRegions(g *grid.Grid[rune]) []*region {
+	// Every cell starts out as its own set; merge it with its right and
+	// lower neighbours when they grow the same plant.
+	ds := unionfind.New[point]()
+	for y, row := range g.Cells {
 		for x, plant := range row {
-			cs, ok := cellsByPlant[plant]
-			if !ok {
-				cs = []*node{}
-				cellsByPlant[plant] = cs
+			p := point{x, y}
+			ds.Add(p)
+			if x+1 < g.W && row[x+1] == plant {
+				ds.Union(p, point{x + 1, y})
+			}
+			if y+1 < g.H && g.Cells[y+1][x] == plant {
+				ds.Union(p, point{x, y + 1})
 			}
-			n := &node{cell: point{x, y}}
-			cellsByPlant[plant] = append(cellsByPlant[plant], n)
 		}
 	}
 
 	var ret []*region
-
-	for plant, nodes := range cellsByPlant {
-		// Now use union-find to merge cells into regions, where
-		// all cells adjoin on 1 or more sides.  Do this per plant
-		// so that we end up with disjoint regions for a particular
-		// plant.  Initially every node is it's own parent.
-		parents := map[*node]*node{}
-		for _, n := range nodes {
-			parents[n] = n
-		}
-		//log.Printf("Plant %s: initial parents:\n%v", string(plant), parents)
-
-		rootFn := func(n *node) *node {
-			var p, root *node
-			p = parents[n]
-			for {
-				if parents[p] == p {
-					root = p
-					break
-				}
-				p = parents[p]
-			}
-			return root
-		}
-
-		// Union
-		for i := 0; i < len(nodes)-1; i++ {
-			for j := i + 1; j < len(nodes); j++ {
-				n1, n2 := nodes[i], nodes[j]
-				if cellAdjoins(n1.cell, n2.cell) {
-					parents[rootFn(n2)] = rootFn(n1)
-				}
-			}
-		}
-		// Find
-		//log.Printf("Plant %s: union-find parents state:\n%v\n", string(plant), parents)
-
-		// Create output regions - need to map each cluster's root to the new region.
-		// 1x1 islands don't have a root in the parents list.
-		regions := map[*node]*region{}
-		for _, n := range nodes {
-			root := rootFn(n)
-			//log.Printf("For node %v found root %v", n, root)
-			reg, ok := regions[root]
-			if !ok {
-				reg = &region{plant: plant}
-				regions[root] = reg
-			}
-			//log.Printf("For root %v found region %v", root, reg)
-			reg.cells = append(reg.cells, n.cell)
-		}
-		//log.Printf("Made regions:\n%v", regions)
-		for _, r := range regions {
-			ret = append(ret, r)
-		}
+	for _, cells := range ds.Components() {
+		first := cells[0]
+		ret = append(ret, &region{plant: g.Cells[first.y][first.x], cells: cells})
 	}
 	return ret
 }
 
-func abs(a int) int {
-	if a >= 0 {
-		return a
-	}
-	return -a
-}
-
-func cellAdjoins(r1, r2 point) bool {
-	if r1.x == r2.x {
-		return abs(r2.y-r1.y) == 1
-	}
-	if r1.y == r2.y {
-		return abs(r2.x-r1.x) == 1
-	}
-	return false
-}
-
 func (r *region) area() int {
 	return len(r.cells)
 }
@@ -272,93 +156,160 @@ func (r *region) woundFences() []wFence {
 }
 
 func (r *region) sides() int {
-	parents := map[wFence]wFence{}
+	// Fences on the same line with the same winding that meet end to end
+	// make up a single side.
+	ds := unionfind.New[wFence]()
 	fences := r.woundFences()
 	for _, f := range fences {
-		parents[f] = f
+		ds.Add(f)
 	}
-	//log.Printf("fences: %v\nparents: %v\n", fences, parents)
-
-	rootFn := func(f wFence) wFence {
-		var p, root wFence
-		p = parents[f]
-		for {
-			if parents[p] == p {
-				root = p
-				break
-			}
-			p = parents[p]
+	for _, f := range fences {
+		if n := f.next(); ds.Has(n) {
+			ds.Union(f, n)
 		}
-		return root
 	}
-	for i := 0; i < len(fences)-1; i++ {
-		for j := 0; j < len(fences); j++ {
-			f1, f2 := fences[i], fences[j]
-			if fenceAdjoins(f1, f2) {
-				parents[rootFn(f1)] = rootFn(f2)
-			}
-		}
+	return ds.Count()
+}
+
+// next is the fence that would continue f's line one cell further along.
+func (f wFence) next() wFence {
+	n := f
+	switch f.f.edge {
+	case top:
+		n.f.cell.x++
+	case right:
+		n.f.cell.y++
 	}
-	//log.Printf("Parents: %v", parents)
-	sides := map[wFence]int{}
-	for _, f := range fences {
-		r := rootFn(f)
-		sides[r]++
+	return n
+}
+
+func totalCost(g *grid.Grid[rune]) int {
+	total := 0
+	for _, c := range grid.Label(g, grid.Equal[rune]).Components {
+		total += c.Area() * c.Sides()
 	}
-	//log.Printf("Sides: %v", sides)
-	return len(sides)
+	return total
 }
 
-func fenceAdjoins(a, b wFence) bool {
-	if a.f.edge != b.f.edge {
-		return false
+func legacyTotalCost(g *grid.Grid[rune]) int {
+	total := 0
+	for _, reg := range findRegions(g) {
+		total += reg.area() * reg.sides()
 	}
-	if a.w != b.w {
-		return false
+	return total
+}
+
+func runTests(input *grid.Grid[rune]) {
+	for _, tc := range []struct {
+		lines []string
+		want  int
+	}{
+		{[]string{"A"}, 4},
+		{[]string{"AAAA", "BBCD", "BBCC", "EEEC"}, 80},
+		{[]string{"OOOOO", "OXOXO", "OOOOO", "OXOXO", "OOOOO"}, 436},
+		{[]string{"AB", "BA"}, 16},
+		{[]string{"EEEEE", "EXXXX", "EEEEE", "EXXXX", "EEEEE"}, 236},
+		{[]string{"AAAAAA", "AAABBA", "AAABBA", "ABBAAA", "ABBAAA", "AAAAAA"}, 368},
+	} {
+		g, err := grid.FromLines(tc.lines, true /*=wantSquare*/)
+		assert(fmt.Sprintf("parse %v err %v", tc.lines, err), err == nil)
+		got := totalCost(g)
+		assert(fmt.Sprintf("%v: cost %d want %d", tc.lines, got, tc.want), got == tc.want)
+		legacy := legacyTotalCost(g)
+		assert(fmt.Sprintf("%v: legacy cost %d want %d", tc.lines, legacy, tc.want), legacy == tc.want)
 	}
-	switch a.f.edge {
-	case top:
-		if a.f.cell.y != b.f.cell.y {
-			return false
-		}
-		if abs(b.f.cell.x-a.f.cell.x) != 1 {
-			return false
-		}
-	case right:
-		if a.f.cell.x != b.f.cell.x {
-			return false
-		}
-		if abs(b.f.cell.y-a.f.cell.y) != 1 {
-			return false
+	got, want := totalCost(input), legacyTotalCost(input)
+	assert(fmt.Sprintf("input: cost %d, legacy cost %d", got, want), got == want)
+}
+
+var bench = flag.Bool("bench", false, "benchmark region finding on a generated garden instead of solving")
+
+// randomGarden makes a w*w garden of clumpy regions: each cell copies its
+// left or upper neighbour most of the time.
+func randomGarden(w int, plants string) *grid.Grid[rune] {
+	rnd := rand.New(rand.NewSource(12))
+	g := grid.New(w, w, '.')
+	for y := 0; y < w; y++ {
+		for x := 0; x < w; x++ {
+			c := rune(plants[rnd.Intn(len(plants))])
+			switch n := rnd.Intn(10); {
+			case n < 4 && x > 0:
+				c = g.Cells[y][x-1]
+			case n < 8 && y > 0:
+				c = g.Cells[y-1][x]
+			}
+			g.Cells[y][x] = c
 		}
 	}
-	return true
+	return g
+}
+
+func runBenchmarks() {
+	// 140x140 is the size of the real puzzle input.
+	g := randomGarden(140, "ABCDEFGH")
+	regions := findRegions(g)
+	for _, b := range []struct {
+		name string
+		f    func()
+	}{
+		{"grid.Label", func() { grid.Label(g, grid.Equal[rune]) }},
+		{"findRegions", func() { findRegions(g) }},
+		{"region.sides", func() {
+			for _, r := range regions {
+				r.sides()
+			}
+		}},
+		{"DisjointSet.Union+Find", func() {
+			ds := unionfind.New[int]()
+			for i := 1; i < 20000; i++ {
+				ds.Union(i-1, i)
+			}
+			for i := 0; i < 20000; i++ {
+				ds.Find(i)
+			}
+		}},
+	} {
+		res := testing.Benchmark(func(tb *testing.B) {
+			for i := 0; i < tb.N; i++ {
+				b.f()
+			}
+		})
+		log.Printf("%-24s %v", b.name, res)
+	}
 }
 
 func main() {
 	log.Println("AoC-2024-day12-part2")
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: main <in file>")
+	flag.Parse()
+	if *bench {
+		runBenchmarks()
+		return
 	}
-	lines, err := readLines(os.Args[1])
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: main [-bench] <in file>")
+	}
+	lines, err := readLines(flag.Arg(0))
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
 
-	g, err := newGrid(lines)
+	g, err := grid.FromLines(lines, true /*=wantSquare*/)
 	if err != nil {
 		log.Fatalf("Failed to parse input: %v", err)
 	}
 	log.Printf("AllPlants:\n%v\n", g)
 
+	runTests(g)
+
+	l := grid.Label(g, grid.Equal[rune])
 	totalCost := 0
-	for _, reg := range g.findRegions() {
-		area := reg.area()
-		perim := reg.perimeter()
-		sides := reg.sides()
+	for _, c := range l.Components {
+		area := c.Area()
+		perim := c.Perimeter
+		sides := c.Sides()
 		cost := area * sides
 		totalCost += cost
-		log.Printf("Plant %s:\n%vArea: %d\nPerimeter: %d\nSides: %d\nCost: %d\n\n", string(reg.plant), g.highlight(reg.plant), area, perim, sides, cost)
+		log.Printf("Plant %s:\n%vArea: %d\nPerimeter: %d\nSides: %d\nCost: %d\n\n", string(plantOf(g, c)), highlight(g, l, c), area, perim, sides, cost)
 	}
 	log.Printf("Total cost: %d", totalCost)
 }
@@ -2,9 +2,13 @@ package main
 
 import (
 	"bufio"
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+
+	"github.com/phad/advent-of-code-2024/lib/grid"
 )
 
 func readLines(f string) ([]string, error) {
@@ -30,3 +34,30 @@ func mustParseInt(s string) int64 {
 	}
 	return v
 }
+
+func assert(s string, b bool) {
+	if !b {
+		log.Fatalf("boom: %v", s)
+	}
+}
+
+func plantOf(g *grid.Grid[rune], c *grid.Component) rune {
+	p := c.Cells[0]
+	return g.Cells[p.Y][p.X]
+}
+
+// highlight shows just the cells of component c, with everything else as '.'.
+func highlight(g *grid.Grid[rune], l *grid.Labelling, c *grid.Component) string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("width:%d height:%d\n", g.W, g.H))
+	for y, row := range g.Cells {
+		for x, r := range row {
+			if l.Labels.Cells[y][x] != c.Label {
+				r = '.'
+			}
+			s.WriteRune(r)
+		}
+		s.WriteRune('\n')
+	}
+	return s.String()
+}
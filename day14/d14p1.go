@@ -1,12 +1,15 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
-	"os"
 	"regexp"
 	"sort"
 	"strings"
+
+	"github.com/phad/advent-of-code-2024/lib/grid"
+	"github.com/phad/advent-of-code-2024/lib/render"
 )
 
 /* Example input
@@ -105,6 +108,21 @@ func debugString(iter int, robots []*robot, a arena) string {
 	return s.String()
 }
 
+// frame draws the robots like debugString does, with the number of robots
+// on each tile or '.' for none.  Counts past densest are drawn as densest,
+// so the busiest tiles get the brightest shade rather than none.
+func frame(robots []*robot, a arena) *grid.Grid[rune] {
+	g := grid.New(a.w, a.h, '.')
+	for _, r := range robots {
+		c := g.Cells[r.p.y][r.p.x]
+		if c == '.' {
+			c = '0'
+		}
+		g.Cells[r.p.y][r.p.x] = min(c+1, densest)
+	}
+	return g
+}
+
 func safetyFactor(robots []*robot, a arena) int {
 	c := map[bool]map[bool]int{false: map[bool]int{}, true: map[bool]int{}}
 	for _, r := range robots {
@@ -128,10 +146,12 @@ func safetyFactor(robots []*robot, a arena) int {
 
 func main() {
 	log.Println("AoC-2024-day14-part1")
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: main <in file>")
+	rec := render.RecorderFlags(palette)
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: main [-png out.png] [-gif out.gif [-every N]] <in file>")
 	}
-	lines, err := readLines(os.Args[1])
+	lines, err := readLines(flag.Arg(0))
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
@@ -152,11 +172,20 @@ func main() {
 
 	for tick := 0; tick < 100; tick++ {
 		log.Printf("\n%s\n%v\n", debugString(tick, robots, a), "") //robots)
+		if rec.Enabled() {
+			rec.Frame(frame(robots, a))
+		}
 		for i := range robots {
 			robots[i].move(a)
 		}
 	}
 	log.Printf("\n%s\n%v\n", debugString(100, robots, a), "") //robots)
+	if rec.Enabled() {
+		rec.Frame(frame(robots, a))
+		if err := rec.Close(); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
 
 	log.Printf("After simulation, robots are:\n%v", robots)
 	log.Printf("Safety factor: %d", safetyFactor(robots, a))
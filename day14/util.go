@@ -2,9 +2,12 @@ package main
 
 import (
 	"bufio"
+	"image/color"
 	"log"
 	"os"
 	"strconv"
+
+	"github.com/phad/advent-of-code-2024/lib/render"
 )
 
 func readLines(f string) ([]string, error) {
@@ -30,3 +33,17 @@ func mustParseInt(s string) int64 {
 	}
 	return v
 }
+
+// palette shades tiles greener the more robots are on them.
+var palette = render.Palette[rune]{
+	Colours: map[rune]color.Color{
+		'1': color.RGBA{0x20, 0x90, 0x30, 0xff},
+		'2': color.RGBA{0x30, 0xc0, 0x40, 0xff},
+		'3': color.RGBA{0x60, 0xe0, 0x60, 0xff},
+		'4': color.RGBA{0xa0, 0xff, 0xa0, 0xff},
+	},
+	Default: color.Black,
+}
+
+// densest is the most robots on a tile that palette has a shade for.
+const densest = '4'
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"strings"
+
+	libgrid "github.com/phad/advent-of-code-2024/lib/grid"
+	"github.com/phad/advent-of-code-2024/lib/render"
 )
 
 /* Example input
@@ -52,6 +56,10 @@ type model struct {
 	moves []move
 	next  int
 	pos   point
+	// onFrame, if set, is shown the arena initially and after every move.
+	onFrame func(*libgrid.Grid[rune])
+	// quiet stops the per-move logging, e.g. while animating.
+	quiet bool
 }
 
 func (m *model) String() string {
@@ -115,7 +123,9 @@ func (m *model) doMove() bool {
 	}
 	move := m.moves[m.next]
 	m.next++
-	log.Printf("Moving: %v", move)
+	if !m.quiet {
+		log.Printf("Moving: %v", move)
+	}
 
 	nextPos, ok := m.innerMove(m.pos, move, true)
 	if ok {
@@ -124,6 +134,9 @@ func (m *model) doMove() bool {
 	} else {
 		//log.Printf("Couldn't move")
 	}
+	if m.onFrame != nil {
+		m.onFrame(m.arena.frame())
+	}
 	return true
 }
 
@@ -202,10 +215,14 @@ func (m *model) gpsSum() int {
 
 func main() {
 	log.Println("AoC-2024-day15-part2")
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: main <in file>")
+	rec := render.RecorderFlags(palette)
+	animate := flag.Bool("animate", false, "play the robot's moves in the terminal")
+	fps := flag.Int("fps", 30, "frames per second for -animate")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: main [-animate [-fps N]] [-png out.png] [-gif out.gif] <in file>")
 	}
-	lines, err := readLines(os.Args[1])
+	lines, err := readLines(flag.Arg(0))
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
@@ -215,9 +232,33 @@ func main() {
 		log.Fatalf("Error: %v", err)
 	}
 
+	var hooks []func(*libgrid.Grid[rune])
+	if rec.Enabled() {
+		hooks = append(hooks, rec.Frame)
+	}
+	if *animate {
+		term := render.NewTerminal(os.Stdout, *fps)
+		term.Highlight = termColours
+		defer term.Close()
+		hooks = append(hooks, func(g *libgrid.Grid[rune]) {
+			term.DrawWithStatus(g, fmt.Sprintf("Move %d/%d", m.next, len(m.moves)))
+		})
+		m.quiet = true
+	}
+	if len(hooks) > 0 {
+		m.onFrame = func(g *libgrid.Grid[rune]) {
+			for _, h := range hooks {
+				h(g)
+			}
+		}
+		m.onFrame(m.arena.frame())
+	}
+
 	max := 0
 	for {
-		log.Printf("%v", m)
+		if !m.quiet {
+			log.Printf("%v", m)
+		}
 		if ok := m.doMove(); !ok {
 			break
 		}
@@ -228,5 +269,8 @@ func main() {
 
 	}
 
+	if err := rec.Close(); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
 	log.Printf("GPS Coords Sum: %d", m.gpsSum())
 }
@@ -3,9 +3,13 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"image/color"
 	"log"
 	"os"
 	"strconv"
+
+	libgrid "github.com/phad/advent-of-code-2024/lib/grid"
+	"github.com/phad/advent-of-code-2024/lib/render"
 )
 
 func readLines(f string) ([]string, error) {
@@ -104,20 +108,29 @@ func (g *grid) swap(p1, p2 point) bool {
 	return true
 }
 
-func (g *grid) highlight(show rune) string {
-	s := fmt.Sprintf("width:%d height:%d\n", g.w, g.h)
-	for _, row := range g.cells {
-		r := make([]rune, g.w)
-		copy(r, row)
-		for i, c := range row {
-			if show != c {
-				r[i] = '.'
-			}
-		}
-		s += string(r)
-		s += "\n"
-	}
-	return s
+// frame shares g's cells with a library grid for the renderers; it is not
+// a copy, so it's only valid until the next move.
+func (g *grid) frame() *libgrid.Grid[rune] {
+	return &libgrid.Grid[rune]{W: g.w, H: g.h, Cells: g.cells}
+}
+
+var palette = render.Palette[rune]{
+	Colours: map[rune]color.Color{
+		'#': color.RGBA{0x60, 0x60, 0x60, 0xff},
+		'O': color.RGBA{0xc8, 0x8c, 0x3c, 0xff},
+		'[': color.RGBA{0xc8, 0x8c, 0x3c, 0xff},
+		']': color.RGBA{0xa0, 0x6e, 0x28, 0xff},
+		'@': color.RGBA{0xff, 0x30, 0x30, 0xff},
+	},
+	Default: color.RGBA{0x10, 0x10, 0x18, 0xff},
+}
+
+var termColours = map[rune]render.Colour{
+	'#': render.Grey,
+	'O': render.Yellow,
+	'[': render.Yellow,
+	']': render.Yellow,
+	'@': render.Red,
 }
 
 type point struct{ x, y int }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// costModel prices the reindeer's moves.
+type costModel struct {
+	// turn is the cost of turning 90 degrees on the spot.
+	turn int
+	// step is the cost of advancing one cell.
+	step int
+	// uTurn, if not 0, allows turning 180 degrees in one move at this cost.
+	uTurn int
+	// terrain overrides step for moving onto cells holding these runes.
+	terrain map[rune]int
+}
+
+var standardCosts = costModel{turn: 1000, step: 1}
+
+func (c costModel) String() string {
+	s := fmt.Sprintf("turn:%d step:%d", c.turn, c.step)
+	if c.uTurn != 0 {
+		s += fmt.Sprintf(" uturn:%d", c.uTurn)
+	}
+	var rs []rune
+	for r := range c.terrain {
+		rs = append(rs, r)
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i] < rs[j] })
+	for _, r := range rs {
+		s += fmt.Sprintf(" %c:%d", r, c.terrain[r])
+	}
+	return s
+}
+
+// stepOnto is the cost of advancing onto a cell holding r.
+func (c costModel) stepOnto(r rune) int {
+	if v, ok := c.terrain[r]; ok {
+		return v
+	}
+	return c.step
+}
+
+// cheapestStep is a lower bound on the cost of any advance, for the A*
+// heuristic.
+func (c costModel) cheapestStep() int {
+	least := c.step
+	for _, v := range c.terrain {
+		least = min(least, v)
+	}
+	return least
+}
+
+func (c costModel) validate() error {
+	if c.turn < 0 || c.step < 0 || c.uTurn < 0 {
+		return fmt.Errorf("costs can't be negative: %v", c)
+	}
+	for r, v := range c.terrain {
+		if r == '#' {
+			return fmt.Errorf("'#' is always a wall, it can't have a cost")
+		}
+		if v < 0 {
+			return fmt.Errorf("costs can't be negative: %v", c)
+		}
+	}
+	return nil
+}
+
+// set applies one setting, as named in a costs file or on the command line.
+func (c *costModel) set(key, val string) error {
+	if strings.HasPrefix(key, "terrain") {
+		r := []rune(strings.TrimSpace(strings.TrimPrefix(key, "terrain")))
+		if len(r) != 1 {
+			return fmt.Errorf("terrain %q: want a single cell rune", string(r))
+		}
+		v, err := strconv.Atoi(val)
+		if err != nil {
+			return err
+		}
+		if c.terrain == nil {
+			c.terrain = map[rune]int{}
+		}
+		c.terrain[r[0]] = v
+		return nil
+	}
+	v, err := strconv.Atoi(val)
+	if err != nil {
+		return err
+	}
+	switch key {
+	case "turn":
+		c.turn = v
+	case "step":
+		c.step = v
+	case "uturn":
+		c.uTurn = v
+	default:
+		return fmt.Errorf("unknown cost %q", key)
+	}
+	return nil
+}
+
+// parseCosts reads a costs file: one "name = value" per line, where name
+// is turn, step, uturn or "terrain X" for a cell rune X.  Lines starting
+// with '#' are comments.  Anything not mentioned keeps its standard cost.
+func parseCosts(lines []string) (costModel, error) {
+	c := standardCosts
+	for i, l := range lines {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(l, "=")
+		if !ok {
+			return c, fmt.Errorf("costs line %d: want name = value, got %q", i+1, l)
+		}
+		if err := c.set(strings.TrimSpace(key), strings.TrimSpace(val)); err != nil {
+			return c, fmt.Errorf("costs line %d: %v", i+1, err)
+		}
+	}
+	return c, c.validate()
+}
+
+type costFlags struct {
+	file              *string
+	turn, step, uTurn *int
+	terrain           *string
+}
+
+func registerCostFlags() *costFlags {
+	return &costFlags{
+		file:    flag.String("costs", "", "read move costs from this file"),
+		turn:    flag.Int("turn", -1, "cost of a 90 degree turn (default 1000)"),
+		step:    flag.Int("step", -1, "cost of advancing one cell (default 1)"),
+		uTurn:   flag.Int("uturn", -1, "cost of a 180 degree turn, if allowed (default not allowed)"),
+		terrain: flag.String("terrain", "", "per-cell step costs, e.g. ~=5,x=20"),
+	}
+}
+
+// load builds the cost model: the standard costs, then the costs file,
+// then any flags.
+func (f *costFlags) load() (costModel, error) {
+	c := standardCosts
+	if *f.file != "" {
+		lines, err := readLines(*f.file)
+		if err != nil {
+			return c, err
+		}
+		if c, err = parseCosts(lines); err != nil {
+			return c, err
+		}
+	}
+	for key, v := range map[string]int{"turn": *f.turn, "step": *f.step, "uturn": *f.uTurn} {
+		if v >= 0 {
+			_ = c.set(key, strconv.Itoa(v))
+		}
+	}
+	if *f.terrain != "" {
+		for _, kv := range strings.Split(*f.terrain, ",") {
+			r, val, ok := strings.Cut(kv, "=")
+			if !ok {
+				return c, fmt.Errorf("-terrain %q: want rune=cost", kv)
+			}
+			if err := c.set("terrain "+r, val); err != nil {
+				return c, fmt.Errorf("-terrain: %v", err)
+			}
+		}
+	}
+	return c, c.validate()
+}
@@ -1,10 +1,15 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+
+	"github.com/phad/advent-of-code-2024/lib/maze"
+	"github.com/phad/advent-of-code-2024/lib/render"
+	"github.com/phad/advent-of-code-2024/lib/search"
 )
 
 /* Example input
@@ -25,306 +30,214 @@ import (
 ###############
 */
 
-type direction rune
-
-const (
-	north = '^'
-	east  = '>'
-	south = 'v'
-	west  = '<'
-)
-
-func (d direction) String() string {
-	return string([]rune{rune(d)})
-}
-
-type move int
-
-const (
-	unknown = iota
-	cwTurn
-	ccwTurn
-	advance
-)
-
-func (m move) String() string {
-	return map[move]string{
-		unknown: "unk",
-		cwTurn:  "cw",
-		ccwTurn: "ccw",
-		advance: "adv",
-	}[m]
-}
-
-type state struct {
-	// pos is the start position before the move
-	pos point
-	// dir is the direction the reindeer faces before the move
-	dir direction
-	// move is the move chosen at position pos
-	mv move
-	// num is how many times the move happened
-	num int
-}
-
-func (s state) String() string {
-	return fmt.Sprintf("[%v(%v)->%v*%d]", s.pos, s.dir, s.mv, s.num)
-}
-
-type model struct {
-	arena  *grid
-	states []state
-	start  point
-	end    point
-	lowest it
-}
-
-func (m *model) String() string {
-	if len(m.states) == 0 {
-		fmt.Sprintf("<init>")
-	}
-	return fmt.Sprintf("Current:%v #prev:%d cost: %d lowest-so-far:%d", m.states[len(m.states)-1], len(m.states)-1, m.cost(), m.lowest)
-}
-
-func newModel(lines []string) (*model, error) {
-	log.Printf(">>newModel")
-	arena, err := newGrid(lines, true /*=wantSquare*/)
-	if err != nil {
-		return nil, err
-	}
-
-	startPos, ok := arena.find('S')
-	if !ok {
-		return nil, fmt.Errorf("Can't find start pos!")
-	}
-
-	endPos, ok := arena.find('E')
-	if !ok {
-		return nil, fmt.Errorf("Can't find end pos!")
-	}
-	log.Printf("<<newModel")
-	return &model{arena: arena, start: startPos, end: endPos, lowest: 99999999999}, nil
-}
-
-// doMove() returns true if a move was made successfully.
-// use atEnd() to find out if we reached the end or not.
-func (m *model) doMove() bool {
-	// Handle initial state
-	if len(m.states) == 0 {
-		m.states = append(m.states, state{
-			pos: m.start,
-			dir: east,
-			mv:  unknown,
-			num: 0,
-		})
-	}
-	return m.innerMove()
-}
-
-// Internals of doMove, extracted for recursion.
-// The state must have at least one state pushed to it.
-// This will be mutated as the reindeer explores different options.
-func (m *model) innerMove() bool {
-	_ = readStdio()
-	log.Printf("innerMove: model=%v", m)
-	log.Printf("\n%v\n", m.arena)
-
-	if m.atEnd() {
-		cost := m.cost()
-		if cost < m.lowest {
-			m.lowest = cost
+func runTests() {
+	fiveByFive := []string{
+		"#####",
+		"#####",
+		"#E.S#",
+		"#####",
+		"#####",
+	}
+	for _, tc := range []struct {
+		name  string
+		lines []string
+		costs costModel
+		found bool
+		want  int
+	}{
+		{"example1", example1, standardCosts, true, 7036},
+		{"example2", example2, standardCosts, true, 11048},
+		{"example1 cheap turns", example1, costModel{turn: 50, step: 1}, true, 386},
+		{"example2 cheap turns", example2, costModel{turn: 50, step: 1}, true, 598},
+		{"example1 muddy", example1, costModel{turn: 1000, step: 1, terrain: map[rune]int{'.': 3}}, true, 7106},
+		// Facing a wall with nowhere to turn, only a U-turn gets out.
+		{"dead end", fiveByFive, standardCosts, false, 0},
+		{"dead end uturn", fiveByFive, costModel{turn: 1000, step: 1, uTurn: 1500}, true, 1502},
+	} {
+		for _, alg := range []search.Algorithm{search.Dijkstra, search.AStar} {
+			m, err := newModel(tc.lines)
+			assert(fmt.Sprintf("%s: newModel err %v", tc.name, err), err == nil)
+			m.costs = tc.costs
+			res := search.Solve(alg, m.problem())
+			assert(fmt.Sprintf("%s/%v: found %t want %t", tc.name, alg, res.Found, tc.found), res.Found == tc.found)
+			assert(fmt.Sprintf("%s/%v: cost %d want %d", tc.name, alg, res.Cost, tc.want), res.Cost == tc.want)
 		}
-		log.Printf("Reached the end at cost of: %d (lowest so far: %d)", cost, m.lowest)
-		return cost < m.lowest
 	}
-	stateSize := len(m.states)
-	if stateSize == 0 {
-		log.Fatalf("invariant violated: state stack must not be empty!")
-	}
-
-	curSt := &(m.states[len(m.states)-1])
 
-	// Figure out if a turn is available
-	// Turns are very costly so prefer to advance first.
-	var hasAdvance bool
-	var availTurns []move
-	// Fast-forward when only advances, no turns, are available.
-	for {
-		hasAdvance = m.canAdvance(*curSt)
-		availTurns = m.canTurn(*curSt)
-		if !hasAdvance || len(availTurns) > 0 {
-			break
+	// BFS counts moves, but mustn't do so by changing the costs of edges
+	// Neighbours keeps and hands back again.
+	m, err := newModel(example1)
+	assert(fmt.Sprintf("newModel err %v", err), err == nil)
+	p := m.problem()
+	kept := map[reindeer][]search.Edge[reindeer]{}
+	next := p.Neighbours
+	p.Neighbours = func(r reindeer) []search.Edge[reindeer] {
+		if _, ok := kept[r]; !ok {
+			kept[r] = next(r)
 		}
-		curSt.mv = advance
-		curSt.num++
-		nextSt := m.prepareNext(*curSt)
-		curSt.pos = nextSt.pos
-		_ = m.arena.set(nextSt.pos, rune(nextSt.dir))
-		log.Printf("innerMove: model=%v", m)
-		log.Printf("\n%v\n", m.arena)
+		return kept[r]
 	}
-	var availMoves []move
-	if hasAdvance {
-		availMoves = append(availMoves, advance)
-	}
-	availMoves = append(availMoves, availTurns...)
-
-	// Try each potential move. Recurse to make this DPS,
-	// which will minimise cost.
-	for _, mv := range availMoves {
-		curSt.mv = mv
-		nextSt := m.prepareNext(*curSt)
-		m.states = append(m.states, nextSt)
-		_ = m.arena.set(nextSt.pos, rune(nextSt.dir))
-		//log.Printf("Trying move %v\nState-stack:\n%v", mv, m.states)
-		if done := m.innerMove(); done {
-			// The move looked ok so continue from here.
-			return true
+	search.SolveAll(search.BFS, p)
+	for r, es := range kept {
+		for i, e := range next(r) {
+			assert(fmt.Sprintf("BFS changed the cost of %v -> %v from %d to %d", r, e.To, e.Cost, es[i].Cost), es[i].Cost == e.Cost)
 		}
-		// Move 'mv' didn't work out, so unwind
-		m.states = m.states[0 : len(m.states)-1]
-		_ = m.arena.set(nextSt.pos, '.')
 	}
-	// Looks like none of the available moves worked - indicate need to backtrack.
-	if len(m.states) != stateSize {
-		log.Fatalf("invariant violated: state stack must be same size as previously (got %d, want %d)", len(m.states), stateSize)
-	}
-	return false
-}
-
-func (m *model) atEnd() bool {
-	return len(m.states) > 0 && m.states[len(m.states)-1].pos == m.end
-}
-
-// Helpers
-
-func (m *model) canAdvance(st state) bool {
-	// Given st.pos and st.dir, calculate the cell we'd
-	// move into.  Return false if it's a wall '#'.
-	nextSt := m.prepareNext(state{pos: st.pos, dir: st.dir, mv: advance})
-	nextCell, ok := m.arena.at(nextSt.pos)
-	if !ok {
-		log.Fatalf("Ran off the grid at %v!", nextSt.pos)
-	}
-	// Check if we've been to nextCell before - if so, avoid.
-	for _, prev := range m.states {
-		if prev.pos == nextSt.pos && prev.dir == nextSt.dir {
-			log.Printf("Not visiting previously visited cell %v in same direction %v", prev.pos, prev.dir)
-			return false
-		}
-	}
-	return nextCell != '#'
-}
 
-func (m *model) canTurn(st state) []move {
-	// Given st.pos and st.dir, calculate if neighbouring
-	// cells (other than the one 'advance' goes to) are
-	// available.
-	var cwPos, ccwPos point
-	switch st.dir {
-	case north:
-		cwPos, ccwPos = point{st.pos.x + 1, st.pos.y}, point{st.pos.x - 1, st.pos.y}
-	case east:
-		cwPos, ccwPos = point{st.pos.x, st.pos.y + 1}, point{st.pos.x, st.pos.y - 1}
-	case south:
-		cwPos, ccwPos = point{st.pos.x - 1, st.pos.y}, point{st.pos.x + 1, st.pos.y}
-	case west:
-		cwPos, ccwPos = point{st.pos.x, st.pos.y - 1}, point{st.pos.x, st.pos.y + 1}
-	}
-	var moves []move
-	if cwCell, ok := m.arena.at(cwPos); ok && cwCell != '#' {
-		moves = append(moves, cwTurn)
-	}
-	if ccwCell, ok := m.arena.at(ccwPos); ok && ccwCell != '#' {
-		moves = append(moves, ccwTurn)
-	}
-	return moves
-}
-
-func (m *model) prepareNext(currSt state) state {
-	next := state{pos: currSt.pos, dir: currSt.dir, mv: unknown}
-	if currSt.mv == advance {
-		switch currSt.dir {
-		case north:
-			next.pos.y--
-		case east:
-			next.pos.x++
-		case south:
-			next.pos.y++
-		case west:
-			next.pos.x--
-		}
-		return next
-	}
-	if currSt.mv == cwTurn {
-		cwRotates := map[direction]direction{
-			north: east,
-			east:  south,
-			south: west,
-			west:  north,
-		}
-		next.dir = cwRotates[currSt.dir]
-		return next
-	}
-	if currSt.mv == ccwTurn {
-		ccwRotates := map[direction]direction{
-			north: west,
-			west:  south,
-			south: east,
-			east:  north,
+	runDebuggerTests()
+	runGeneratedMazeTests()
+
+	c, err := parseCosts([]string{
+		"# cheap turns, and slow going through water",
+		"turn = 50",
+		"",
+		"uturn=75",
+		"terrain ~ = 4",
+	})
+	assert(fmt.Sprintf("parseCosts err %v", err), err == nil)
+	assert(fmt.Sprintf("parseCosts got %v", c), c.String() == "turn:50 step:1 uturn:75 ~:4")
+	for _, bad := range [][]string{{"turn 50"}, {"speed = 3"}, {"step = -1"}, {"terrain # = 1"}, {"terrain ab = 1"}} {
+		_, err := parseCosts(bad)
+		assert(fmt.Sprintf("parseCosts(%q) should fail", bad), err != nil)
+	}
+}
+
+// runGeneratedMazeTests checks that generated mazes load, and that the
+// searches agree on them.
+func runGeneratedMazeTests() {
+	for _, o := range []maze.Options{
+		{W: 41, H: 41, Seed: 1},
+		{W: 41, H: 41, Seed: 2, Braid: 0.5},
+		{W: 41, H: 41, Seed: 3, Braid: 1},
+	} {
+		g, err := maze.Reindeer(o)
+		assert(fmt.Sprintf("%+v: Reindeer err %v", o, err), err == nil)
+		again, _ := maze.Reindeer(o)
+		assert(fmt.Sprintf("%+v: not the same maze twice", o), g.String() == again.String())
+		reseeded := o
+		reseeded.Seed += 100
+		other, _ := maze.Reindeer(reseeded)
+		assert(fmt.Sprintf("%+v: same maze as seed %d", o, reseeded.Seed), g.String() != other.String())
+
+		var lines []string
+		for _, row := range g.Cells {
+			lines = append(lines, string(row))
 		}
-		next.dir = ccwRotates[currSt.dir]
-		return next
-	}
-	// unknown - no change.
-	return currSt
-}
-
-func (m *model) cost() int {
-	sum := 0
-	for _, st := range m.states {
-		if st.mv == cwTurn || st.mv == ccwTurn {
-			sum += 1000
-			continue
+		m, err := newModel(lines)
+		assert(fmt.Sprintf("%+v: newModel err %v", o, err), err == nil)
+		dijkstra := search.Solve(search.Dijkstra, m.problem())
+		astar := search.Solve(search.AStar, m.problem())
+		assert(fmt.Sprintf("%+v: dijkstra %v astar %v", o, dijkstra, astar), dijkstra.Found && astar.Found && dijkstra.Cost == astar.Cost)
+	}
+	_, err := maze.Reindeer(maze.Options{W: 41, H: 31})
+	assert("Reindeer should only make square mazes", err != nil)
+}
+
+// runDebuggerTests drives the debugger from scripts, and checks where it
+// paused.
+func runDebuggerTests() {
+	for _, tc := range []struct {
+		name     string
+		step     bool
+		cont     int
+		breakAts []point
+		script   string
+		// want are the pause lines expected, in order.
+		want []string
+	}{
+		{"break, step, continue", false, 0, []point{{3, 13}}, "s\ns\nc\n",
+			[]string{"#3 at (3,13)> cost 2", "#4 at (1,13)^ cost 1000", "#5 at (1,12)^ cost 1001"}},
+		{"continue N", true, 4, nil, "c 10\nq\n",
+			[]string{"#5 at", "#15 at"}},
+		{"continue N without step", false, 4, nil, "s\nq\n",
+			[]string{"#5 at", "#6 at"}},
+		{"break added at prompt", true, 0, nil, "b 13,2\nc\nq\n",
+			[]string{"#1 at (1,13)>", "at (13,2)^"}},
+		{"out of input", true, 0, nil, "",
+			[]string{"#1 at"}},
+	} {
+		m, err := newModel(example1)
+		assert(fmt.Sprintf("%s: newModel err %v", tc.name, err), err == nil)
+		f := &debugFlags{step: &tc.step, cont: &tc.cont, breakAts: tc.breakAts}
+		assert(fmt.Sprintf("%s: debugger not enabled", tc.name), f.enabled())
+		var out strings.Builder
+		d := newDebugger(m, f, strings.NewReader(tc.script), &out)
+		p := m.problem()
+		p.Observer = d.observe
+		res := search.Solve(search.Dijkstra, p)
+		assert(fmt.Sprintf("%s: cost %d want 7036", tc.name, res.Cost), res.Cost == 7036)
+
+		var pauses []string
+		for _, l := range strings.Split(out.String(), "\n") {
+			// Scripted commands aren't echoed, so output follows the prompt.
+			for strings.HasPrefix(l, "(debug) ") {
+				l = strings.TrimPrefix(l, "(debug) ")
+			}
+			if strings.HasPrefix(l, "#") && strings.Contains(l, " at ") {
+				pauses = append(pauses, l)
+			}
 		}
-		if st.mv == advance {
-			sum += st.num
+		got := strings.Join(pauses, "\n")
+		assert(fmt.Sprintf("%s: %d pauses want %d:\n%s", tc.name, len(pauses), len(tc.want), got), len(pauses) == len(tc.want))
+		for i, w := range tc.want {
+			assert(fmt.Sprintf("%s: pause %d want %q, got:\n%s", tc.name, i, w, got), strings.Contains(pauses[i], w))
 		}
 	}
-	return sum
-}
-
-func readStdio() string {
-	reader := bufio.NewReader(os.Stdin)
-	in, _ := reader.ReadString('\n')
-	return in
 }
 
 func main() {
 	log.Println("AoC-2024-day16-part1")
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: main <in file>")
+	algName := flag.String("alg", "dijkstra", "search algorithm: bfs, dijkstra or astar")
+	cf := registerCostFlags()
+	df := registerDebugFlags()
+	ex := render.ExplorerFlags()
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: main [-alg bfs|dijkstra|astar] [-costs file] [-turn N] [-step N] [-uturn N] [-terrain r=N,...] [-debug-step] [-debug-continue N] [-debug-break-at x,y] [-explore] [-explore-png file] [-explore-gif file] <in file>")
+	}
+	alg, err := search.ParseAlgorithm(*algName)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	costs, err := cf.load()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
 	}
-	lines, err := readLines(os.Args[1])
+	lines, err := readLines(flag.Arg(0))
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
 
+	runTests()
+
 	m, err := newModel(lines)
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
+	m.costs = costs
+	log.Printf("Costs: %v", costs)
 
-	for {
-		if ok := m.doMove(); !ok {
-			log.Printf("doMove()=false: we're probably not done yet?")
-			break
-		}
-		if m.atEnd() {
-			log.Printf("Detected that we reached the end!")
-			break
+	p := m.problem()
+	if df.enabled() {
+		p.Observer = newDebugger(m, df, os.Stdin, os.Stdout).observe
+	}
+	var rec *search.Recording[reindeer]
+	if ex.Enabled() {
+		rec = &search.Recording[reindeer]{Every: ex.Every()}
+		p = search.Record(p, rec)
+	}
+	res := search.Solve(alg, p)
+	if !res.Found {
+		log.Fatalf("No route from %v to %v!", m.start, m.end)
+	}
+	if rec != nil {
+		rec.AddPath(res.Path)
+		if err := ex.Write(m.exploration(rec)); err != nil {
+			log.Fatalf("Error: %v", err)
 		}
 	}
-
-	log.Printf("Cost: %d", m.cost())
+	m.markPath(res.Path)
+	log.Printf("\n%v\n", m.arena)
+	log.Printf("Search (%v): %v", alg, res)
+	log.Printf("Cost: %d", res.Cost)
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/phad/advent-of-code-2024/lib/render"
+	"github.com/phad/advent-of-code-2024/lib/search"
+)
+
+/* Example input: see d16p1.go.
+
+Count the tiles that are part of at least one of the cheapest routes
+from S to E.
+*/
+
+func runTests() {
+	for _, tc := range []struct {
+		name  string
+		lines []string
+		cost  int
+		tiles int
+	}{
+		{"example1", example1, 7036, 45},
+		{"example2", example2, 11048, 64},
+	} {
+		for _, alg := range []search.Algorithm{search.Dijkstra, search.AStar} {
+			m, err := newModel(tc.lines)
+			assert(fmt.Sprintf("%s: newModel err %v", tc.name, err), err == nil)
+			res := search.SolveAll(alg, m.problem())
+			assert(fmt.Sprintf("%s/%v: cost %d want %d", tc.name, alg, res.Cost, tc.cost), res.Found && res.Cost == tc.cost)
+			tiles := m.markTiles(res.OnBestPaths())
+			assert(fmt.Sprintf("%s/%v: %d tiles want %d", tc.name, alg, tiles, tc.tiles), tiles == tc.tiles)
+		}
+	}
+}
+
+func main() {
+	log.Println("AoC-2024-day16-part2")
+	algName := flag.String("alg", "dijkstra", "search algorithm: dijkstra or astar")
+	cf := registerCostFlags()
+	df := registerDebugFlags()
+	ex := render.ExplorerFlags()
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: main [-alg dijkstra|astar] [-costs file] [-turn N] [-step N] [-uturn N] [-terrain r=N,...] [-debug-step] [-debug-continue N] [-debug-break-at x,y] [-explore] [-explore-png file] [-explore-gif file] <in file>")
+	}
+	alg, err := search.ParseAlgorithm(*algName)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if alg == search.BFS {
+		// BFS counts moves rather than cost, so its routes aren't the
+		// cheapest.
+		log.Fatal("Error: -alg bfs can't find the cheapest routes: use dijkstra or astar")
+	}
+	costs, err := cf.load()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	lines, err := readLines(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	runTests()
+
+	m, err := newModel(lines)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	m.costs = costs
+	log.Printf("Costs: %v", costs)
+
+	p := m.problem()
+	if df.enabled() {
+		p.Observer = newDebugger(m, df, os.Stdin, os.Stdout).observe
+	}
+	var rec *search.Recording[reindeer]
+	if ex.Enabled() {
+		rec = &search.Recording[reindeer]{Every: ex.Every()}
+		p = search.Record(p, rec)
+	}
+	res := search.SolveAll(alg, p)
+	if !res.Found {
+		log.Fatalf("No route from %v to %v!", m.start, m.end)
+	}
+	if rec != nil {
+		rec.AddPath(res.OnBestPaths())
+		if err := ex.Write(m.exploration(rec)); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+	tiles := m.markTiles(res.OnBestPaths())
+	log.Printf("\n%v\n", m.arena)
+	log.Printf("Cheapest cost: %d (%d states expanded)", res.Cost, res.Expanded)
+	log.Printf("Tiles on a cheapest route: %d", tiles)
+}
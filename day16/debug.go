@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	libgrid "github.com/phad/advent-of-code-2024/lib/grid"
+	"github.com/phad/advent-of-code-2024/lib/render"
+)
+
+// debugger pauses the search so it can be stepped through, showing the
+// arena with the state being expanded and the frontier still to do.
+//
+// At each pause it reads a command:
+//
+//	s, step, or just enter   expand one more state
+//	c, continue [N]          run N more expansions, or until a breakpoint
+//	b, break X,Y             also pause whenever the reindeer is at X,Y
+//	q, quit                  stop pausing and run to the end
+type debugger struct {
+	m   *model
+	in  *bufio.Scanner
+	out io.Writer
+	// stepping pauses after skip more expansions.
+	stepping bool
+	skip     int
+	breakAt  map[point]bool
+	count    int
+	quit     bool
+	term     *render.Terminal
+}
+
+type debugFlags struct {
+	step     *bool
+	cont     *int
+	breakAts []point
+}
+
+func registerDebugFlags() *debugFlags {
+	f := &debugFlags{
+		step: flag.Bool("debug-step", false, "pause the search at every expansion"),
+		cont: flag.Int("debug-continue", 0, "run this many expansions, then pause at every one"),
+	}
+	flag.Func("debug-break-at", "pause the search when the reindeer reaches x,y (repeatable)", func(s string) error {
+		p, err := parsePoint(s)
+		if err == nil {
+			f.breakAts = append(f.breakAts, p)
+		}
+		return err
+	})
+	return f
+}
+
+// enabled reports whether any flag asks for the debugger.  Continuing N
+// expansions implies stepping once they're done.
+func (f *debugFlags) enabled() bool {
+	return *f.step || *f.cont > 0 || len(f.breakAts) > 0
+}
+
+func parsePoint(s string) (point, error) {
+	xs, ys, ok := strings.Cut(strings.TrimSpace(s), ",")
+	if !ok {
+		return point{}, fmt.Errorf("want x,y got %q", s)
+	}
+	x, err := strconv.Atoi(xs)
+	if err != nil {
+		return point{}, err
+	}
+	y, err := strconv.Atoi(ys)
+	if err != nil {
+		return point{}, err
+	}
+	return point{x, y}, nil
+}
+
+func newDebugger(m *model, f *debugFlags, in io.Reader, out io.Writer) *debugger {
+	d := &debugger{
+		m:        m,
+		in:       bufio.NewScanner(in),
+		out:      out,
+		stepping: *f.step || *f.cont > 0,
+		skip:     *f.cont,
+		breakAt:  map[point]bool{},
+		term:     render.NewTerminal(out, 0),
+	}
+	d.term.Highlight = map[rune]render.Colour{'#': render.Grey, 'S': render.Green, 'E': render.Green}
+	for _, p := range f.breakAts {
+		d.breakAt[p] = true
+	}
+	return d
+}
+
+// observe is the search's Observer.
+func (d *debugger) observe(r reindeer, cost int, frontier func() []reindeer) {
+	d.count++
+	if d.quit {
+		return
+	}
+	pause := d.breakAt[r.pos]
+	if d.stepping {
+		if d.skip > 0 {
+			d.skip--
+		} else {
+			pause = true
+		}
+	}
+	if !pause {
+		return
+	}
+	d.show(r, cost, frontier())
+	d.prompt()
+}
+
+func (d *debugger) show(r reindeer, cost int, frontier []reindeer) {
+	var fps []libgrid.Point
+	for _, f := range frontier {
+		fps = append(fps, f.pos.lib())
+	}
+	fmt.Fprintf(d.out, "#%d at %v cost %d, frontier %d\n", d.count, r, cost, len(frontier))
+	fmt.Fprint(d.out, d.term.Render(d.m.arena.frame(),
+		render.Path(fps, render.Cyan),
+		render.Marker(r.pos.lib(), rune(r.dir), render.Red)))
+}
+
+func (d *debugger) prompt() {
+	for {
+		fmt.Fprint(d.out, "(debug) ")
+		if !d.in.Scan() {
+			// Out of input: let the search finish by itself.
+			fmt.Fprintln(d.out)
+			d.quit = true
+			return
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(d.in.Text()), " ")
+		switch cmd {
+		case "", "s", "step":
+			d.stepping, d.skip = true, 0
+			return
+		case "c", "continue":
+			d.stepping, d.skip = false, 0
+			if arg != "" {
+				n, err := strconv.Atoi(arg)
+				if err != nil || n < 1 {
+					fmt.Fprintf(d.out, "continue: want a count, got %q\n", arg)
+					continue
+				}
+				d.stepping, d.skip = true, n-1
+			}
+			return
+		case "b", "break":
+			p, err := parsePoint(arg)
+			if err != nil {
+				fmt.Fprintf(d.out, "break: %v\n", err)
+				continue
+			}
+			d.breakAt[p] = true
+			fmt.Fprintf(d.out, "breakpoint at %v\n", p)
+		case "q", "quit":
+			d.quit = true
+			return
+		default:
+			fmt.Fprintf(d.out, "unknown command %q: try step, continue [N], break X,Y or quit\n", cmd)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+
+	libgrid "github.com/phad/advent-of-code-2024/lib/grid"
+	"github.com/phad/advent-of-code-2024/lib/render"
+	"github.com/phad/advent-of-code-2024/lib/search"
+)
+
+type direction rune
+
+const (
+	north = '^'
+	east  = '>'
+	south = 'v'
+	west  = '<'
+)
+
+func (d direction) String() string {
+	return string([]rune{rune(d)})
+}
+
+var cwRotates = map[direction]direction{
+	north: east,
+	east:  south,
+	south: west,
+	west:  north,
+}
+
+var ccwRotates = map[direction]direction{
+	north: west,
+	west:  south,
+	south: east,
+	east:  north,
+}
+
+// step returns the cell one move from p in direction d.
+func (d direction) step(p point) point {
+	switch d {
+	case north:
+		p.y--
+	case east:
+		p.x++
+	case south:
+		p.y++
+	case west:
+		p.x--
+	}
+	return p
+}
+
+// reindeer is the search state: where the reindeer is and which way it's
+// facing.
+type reindeer struct {
+	pos point
+	dir direction
+}
+
+func (r reindeer) String() string {
+	return fmt.Sprintf("%v%v", r.pos, r.dir)
+}
+
+type model struct {
+	arena *grid
+	start point
+	end   point
+	costs costModel
+}
+
+func newModel(lines []string) (*model, error) {
+	arena, err := newGrid(lines, true /*=wantSquare*/)
+	if err != nil {
+		return nil, err
+	}
+
+	startPos, ok := arena.find('S')
+	if !ok {
+		return nil, fmt.Errorf("Can't find start pos!")
+	}
+
+	endPos, ok := arena.find('E')
+	if !ok {
+		return nil, fmt.Errorf("Can't find end pos!")
+	}
+	return &model{arena: arena, start: startPos, end: endPos, costs: standardCosts}, nil
+}
+
+func (m *model) open(p point) bool {
+	c, ok := m.arena.at(p)
+	return ok && c != '#'
+}
+
+// neighbours are the moves the reindeer can make: advance, or turn on
+// the spot, priced by m.costs.  Turns are only worth making towards an
+// open cell.
+func (m *model) neighbours(r reindeer) []search.Edge[reindeer] {
+	var moves []search.Edge[reindeer]
+	if next := r.dir.step(r.pos); m.open(next) {
+		c, _ := m.arena.at(next)
+		moves = append(moves, search.Edge[reindeer]{To: reindeer{next, r.dir}, Cost: m.costs.stepOnto(c)})
+	}
+	for _, d := range []direction{cwRotates[r.dir], ccwRotates[r.dir]} {
+		if m.open(d.step(r.pos)) {
+			moves = append(moves, search.Edge[reindeer]{To: reindeer{r.pos, d}, Cost: m.costs.turn})
+		}
+	}
+	if back := cwRotates[cwRotates[r.dir]]; m.costs.uTurn > 0 && m.open(back.step(r.pos)) {
+		moves = append(moves, search.Edge[reindeer]{To: reindeer{r.pos, back}, Cost: m.costs.uTurn})
+	}
+	return moves
+}
+
+func (m *model) problem() search.Problem[reindeer] {
+	least := m.costs.cheapestStep()
+	return search.Problem[reindeer]{
+		Start:      reindeer{m.start, east},
+		Neighbours: m.neighbours,
+		Goal:       func(r reindeer) bool { return r.pos == m.end },
+		// Every remaining cell costs at least the cheapest step.
+		Heuristic: func(r reindeer) int { return least * (abs(m.end.x-r.pos.x) + abs(m.end.y-r.pos.y)) },
+	}
+}
+
+// markPath draws the reindeer's route onto the arena.
+func (m *model) markPath(path []reindeer) {
+	for _, r := range path {
+		if r.pos != m.start && r.pos != m.end {
+			_ = m.arena.set(r.pos, rune(r.dir))
+		}
+	}
+}
+
+// markTiles puts an 'O' on every cell any of the states is in, and
+// returns how many distinct cells that was.
+func (m *model) markTiles(states []reindeer) int {
+	tiles := map[point]bool{}
+	for _, r := range states {
+		tiles[r.pos] = true
+		_ = m.arena.set(r.pos, 'O')
+	}
+	return len(tiles)
+}
+
+// exploration lays a recorded search over the maze. Call it before marking
+// paths on the arena, as it shares the arena's cells.
+func (m *model) exploration(rec *search.Recording[reindeer]) *render.Exploration {
+	e := render.NewExploration(m.arena.frame(), rec, func(r reindeer) libgrid.Point { return r.pos.lib() })
+	e.Wall = '#'
+	return e
+}
@@ -6,6 +6,8 @@ import (
 	"log"
 	"os"
 	"strconv"
+
+	libgrid "github.com/phad/advent-of-code-2024/lib/grid"
 )
 
 func readLines(f string) ([]string, error) {
@@ -65,7 +67,7 @@ func (g *grid) String() string {
 }
 
 func (g *grid) set(p point, r rune) bool {
-	if p.y < 0 || p.y >= g.h || p.x < 0 || p.y >= g.w {
+	if p.y < 0 || p.y >= g.h || p.x < 0 || p.x >= g.w {
 		return false
 	}
 	g.cells[p.y][p.x] = r
@@ -74,7 +76,7 @@ func (g *grid) set(p point, r rune) bool {
 
 func (g *grid) at(p point) (rune, bool) {
 	nope := rune(0)
-	if p.y < 0 || p.y >= g.h || p.x < 0 || p.y >= g.w {
+	if p.y < 0 || p.y >= g.h || p.x < 0 || p.x >= g.w {
 		return nope, false
 	}
 	return g.cells[p.y][p.x], true
@@ -102,10 +104,10 @@ func (g *grid) findAll(r rune, f func(point) bool) {
 }
 
 func (g *grid) swap(p1, p2 point) bool {
-	if p1.y < 0 || p1.y >= g.h || p1.x < 0 || p1.y >= g.w {
+	if p1.y < 0 || p1.y >= g.h || p1.x < 0 || p1.x >= g.w {
 		return false
 	}
-	if p2.y < 0 || p2.y >= g.h || p2.x < 0 || p2.y >= g.w {
+	if p2.y < 0 || p2.y >= g.h || p2.x < 0 || p2.x >= g.w {
 		return false
 	}
 	g.cells[p2.y][p2.x], g.cells[p1.y][p1.x] = g.cells[p1.y][p1.x], g.cells[p2.y][p2.x]
@@ -129,3 +131,67 @@ func (g *grid) highlight(show rune) string {
 }
 
 type point struct{ x, y int }
+
+func (p point) String() string {
+	return fmt.Sprintf("(%d,%d)", p.x, p.y)
+}
+
+func (p point) lib() libgrid.Point {
+	return libgrid.Point{X: p.x, Y: p.y}
+}
+
+// frame shares g's cells with a library grid for the renderers.
+func (g *grid) frame() *libgrid.Grid[rune] {
+	return &libgrid.Grid[rune]{W: g.w, H: g.h, Cells: g.cells}
+}
+
+func abs(a int) int {
+	if a >= 0 {
+		return a
+	}
+	return -a
+}
+
+func assert(s string, b bool) {
+	if !b {
+		log.Fatalf("boom: %v", s)
+	}
+}
+
+var example1 = []string{
+	"###############",
+	"#.......#....E#",
+	"#.#.###.#.###.#",
+	"#.....#.#...#.#",
+	"#.###.#####.#.#",
+	"#.#.#.......#.#",
+	"#.#.#####.###.#",
+	"#...........#.#",
+	"###.#.#####.#.#",
+	"#...#.....#.#.#",
+	"#.#.#.###.#.#.#",
+	"#.....#...#.#.#",
+	"#.###.#.#.#.#.#",
+	"#S..#.....#...#",
+	"###############",
+}
+
+var example2 = []string{
+	"#################",
+	"#...#...#...#..E#",
+	"#.#.#.#.#.#.#.#.#",
+	"#.#.#.#...#...#.#",
+	"#.#.#.#.###.#.#.#",
+	"#...#.#.#.....#.#",
+	"#.#.#.#.#.#####.#",
+	"#.#...#.#.#.....#",
+	"#.#.#####.#.###.#",
+	"#.#.#.......#...#",
+	"#.#.###.#####.###",
+	"#.#.#...#.....#.#",
+	"#.#.#.#####.###.#",
+	"#.#.#.........#.#",
+	"#.#.#.#########.#",
+	"#S#.............#",
+	"#################",
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// asm assembles a day 17 program, and writes it out as a puzzle input:
+//
+//	go run asm.go assembler.go tracing.go errors.go computer.go listing.go util.go [-a N] [-b N] [-c N] <source file>
+func main() {
+	a := flag.Int("a", 0, "initial value of register A")
+	b := flag.Int("b", 0, "initial value of register B")
+	c := flag.Int("c", 0, "initial value of register C")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: asm [-a N] [-b N] [-c N] <source file>")
+	}
+	src, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	program, err := assemble(string(src))
+	if err != nil {
+		log.Fatalf("Error: %s: %v", flag.Arg(0), err)
+	}
+	fmt.Printf("Register A: %d\nRegister B: %d\nRegister C: %d\n\nProgram: %s\n", *a, *b, *c, formatProgram(program))
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// assemble reads the assembly language that disassemble writes, one
+// instruction per line:
+//
+//	; Comments run from ; or # to the end of the line.
+//	loop:           ; a label, for jnz to use
+//	    bst A       ; combo operands are 0-3, A, B or C
+//	    bxl 7       ; bxl's operand is a literal, 0-7
+//	    bxc         ; bxc's operand is ignored, and can be left out
+//	    jnz loop    ; jnz takes a label or an address
+//
+// An instruction can start with its address, as listings do; it has to be
+// the right one. A label can also go before an instruction on its line.
+// Errors are *ErrParse, saying which line is wrong.
+func assemble(src string) ([]operation, error) {
+	type fixup struct {
+		line  int
+		at    int
+		label string
+	}
+	var program []operation
+	labels := map[string]int{}
+	var fixups []fixup
+	mnemonics := map[string]opcode{}
+	for o := adv; o < invalidOpcode; o++ {
+		mnemonics[opcode(o).String()] = opcode(o)
+	}
+
+	for n, line := range strings.Split(src, "\n") {
+		n++
+		if i := strings.IndexAny(line, ";#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 0 && strings.HasSuffix(fields[0], ":") {
+			name := strings.TrimSuffix(fields[0], ":")
+			if !isLabel(name) {
+				return nil, parseErrorf(n, "bad label %q", name)
+			}
+			if _, dup := labels[name]; dup {
+				return nil, parseErrorf(n, "label %q defined twice", name)
+			}
+			labels[name] = 2 * len(program)
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if addr, err := strconv.Atoi(fields[0]); err == nil {
+			if addr != 2*len(program) {
+				return nil, parseErrorf(n, "says address %d, but the instruction is at %d", addr, 2*len(program))
+			}
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			return nil, parseErrorf(n, "address with no instruction")
+		}
+		opc, ok := mnemonics[strings.ToLower(fields[0])]
+		if !ok {
+			return nil, parseErrorf(n, "unknown instruction %q", fields[0])
+		}
+		args := fields[1:]
+		op := operation{opcode: opc}
+		switch {
+		case len(args) > 1:
+			return nil, parseErrorf(n, "%v takes one operand, got %d", opc, len(args))
+		case len(args) == 0 && opc.operandKind() != ignoredOperand:
+			return nil, parseErrorf(n, "%v needs an operand", opc)
+		case len(args) == 0:
+			// bxc with nothing after it.
+		case opc.operandKind() == comboOperand:
+			v, err := parseCombo(args[0])
+			if err != nil {
+				return nil, parseErrorf(n, "%v: %v", opc, err)
+			}
+			op.operand = v
+		case opc == jnz && isLabel(args[0]):
+			fixups = append(fixups, fixup{n, len(program), args[0]})
+		default:
+			v, err := strconv.Atoi(args[0])
+			if err != nil || v < 0 || v > 7 {
+				return nil, parseErrorf(n, "%v: operand %q isn't 0-7", opc, args[0])
+			}
+			op.operand = operand(v)
+		}
+		program = append(program, op)
+	}
+
+	for _, f := range fixups {
+		addr, ok := labels[f.label]
+		if !ok {
+			return nil, parseErrorf(f.line, "no label %q", f.label)
+		}
+		if addr > 7 {
+			return nil, parseErrorf(f.line, "label %q is at %d, too far to jump to in 3 bits", f.label, addr)
+		}
+		program[f.at].operand = operand(addr)
+	}
+	return program, nil
+}
+
+// isLabel allows names made of letters, digits and _, not starting with a
+// digit.
+func isLabel(s string) bool {
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for _, r := range s {
+		if !(r == '_' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseCombo(s string) (operand, error) {
+	switch strings.ToUpper(s) {
+	case "0", "1", "2", "3":
+		return operand(s[0] - '0'), nil
+	case "A":
+		return regA, nil
+	case "B":
+		return regB, nil
+	case "C":
+		return regC, nil
+	case "?7":
+		return halt, nil
+	}
+	return 0, fmt.Errorf("combo operand %q isn't 0-3, A, B or C", s)
+}
+
+// mustAssemble is for programs written into the code, e.g. in tests.
+func mustAssemble(src string) []operation {
+	program, err := assemble(src)
+	if err != nil {
+		panic(fmt.Sprintf("assemble: %v", err))
+	}
+	return program
+}
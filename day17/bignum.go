@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+)
+
+// exactBits is how big registers can get for computer to get the right
+// answers: its division goes through float64, which only holds integers
+// exactly up to 2^53.
+const exactBits = 53
+
+// bigComputer is computer with registers of any size, and division by
+// shifting, so it's exact however big A is. It's slower, so only used
+// for registers past exactBits, or when asked for.
+type bigComputer struct {
+	A, B, C *big.Int
+	ip      int
+	program []operation
+	halt    bool
+	output  []int
+	// verbose and limit are like computer's.
+	verbose bool
+	limit   int
+}
+
+func initBigComputer(a, b, c *big.Int, program []operation) *bigComputer {
+	return &bigComputer{
+		A:       new(big.Int).Set(a),
+		B:       new(big.Int).Set(b),
+		C:       new(big.Int).Set(c),
+		program: append([]operation(nil), program...),
+	}
+}
+
+// parseBigInput is parseInput for registers of any size.
+func parseBigInput(in []string) (*bigComputer, error) {
+	p, err := readInput(in)
+	if err != nil {
+		return nil, err
+	}
+	return initBigComputer(p.regs[0], p.regs[1], p.regs[2], p.program), nil
+}
+
+// runner is what's needed of either computer to run a puzzle input.
+type runner interface {
+	execute() error
+	out() string
+}
+
+// loadComputer reads the puzzle input as a computer, or as a bigComputer
+// if forceBig or if any register is too big for computer to be exact with.
+func loadComputer(in []string, forceBig bool) (runner, error) {
+	p, err := readInput(in)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range p.regs {
+		forceBig = forceBig || v.BitLen() > exactBits
+	}
+	if forceBig {
+		return initBigComputer(p.regs[0], p.regs[1], p.regs[2], p.program), nil
+	}
+	return initComputer(int(p.regs[0].Int64()), int(p.regs[1].Int64()), int(p.regs[2].Int64()), p.program), nil
+}
+
+// bigState is a bigComputer's state, comparable for loopDetector.
+type bigState struct {
+	ip      int
+	A, B, C string
+}
+
+func (c *bigComputer) execute() error {
+	var loops loopDetector[bigState]
+	for count := 1; ; count++ {
+		if c.limit > 0 && count > c.limit {
+			return errStepLimit
+		}
+		if loops.repeats(bigState{c.ip, c.A.Text(16), c.B.Text(16), c.C.Text(16)}) {
+			return fmt.Errorf("ip %d, A=%v B=%v C=%v again: %w", c.ip, c.A, c.B, c.C, ErrLoop)
+		}
+		done, err := c.step()
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// step is computer's step.
+func (c *bigComputer) step() (done bool, err error) {
+	if c.halt {
+		return false, errHalt
+	}
+	if c.ip < 0 || c.ip%2 != 0 {
+		return false, fmt.Errorf("ip %d: %w", c.ip, ErrBadJump)
+	}
+	if c.ip >= 2*len(c.program) {
+		c.halt = true
+		return true, nil
+	}
+	op := c.program[c.ip/2]
+	if op.opcode < adv || op.opcode >= invalidOpcode || op.operand < lit0 || op.operand >= invalidOperand {
+		return false, fmt.Errorf("ip %d: %d,%d: %w", c.ip, int(op.opcode), int(op.operand), ErrInvalidOpcode)
+	}
+
+	v := big.NewInt(int64(op.operand))
+	if op.opcode.operandKind() == comboOperand {
+		switch op.operand {
+		case regA:
+			v = c.A
+		case regB:
+			v = c.B
+		case regC:
+			v = c.C
+		case halt:
+			return false, fmt.Errorf("ip %d: %v: %w", c.ip, op.text(), ErrReservedOperand)
+		}
+	}
+	// shifted is A / 2^v. Shifting by more than A has bits leaves 0.
+	shifted := func() *big.Int {
+		if !v.IsInt64() || v.Int64() > int64(c.A.BitLen()) {
+			return new(big.Int)
+		}
+		return new(big.Int).Rsh(c.A, uint(v.Int64()))
+	}
+	mod8 := func(x *big.Int) int {
+		return int(new(big.Int).And(x, big.NewInt(7)).Int64())
+	}
+
+	c.logf(">> %v %v", op.opcode, op.operand)
+	next := c.ip + 2
+	switch op.opcode {
+	case adv:
+		c.A = shifted()
+	case bxl:
+		c.B = new(big.Int).Xor(c.B, v)
+	case bst:
+		c.B = big.NewInt(int64(mod8(v)))
+	case jnz:
+		if c.A.Sign() != 0 {
+			next = int(op.operand)
+		}
+	case bxc:
+		c.B = new(big.Int).Xor(c.B, c.C)
+	case out:
+		c.output = append(c.output, mod8(v))
+	case bdv:
+		c.B = shifted()
+	case cdv:
+		c.C = shifted()
+	}
+	c.logf("A=%v B=%v C=%v output=%v", c.A, c.B, c.C, c.output)
+	c.ip = next
+	return false, nil
+}
+
+func (c *bigComputer) logf(format string, v ...any) {
+	if c.verbose {
+		log.Printf(format, v...)
+	}
+}
+
+func (c *bigComputer) out() string {
+	var s []string
+	for _, v := range c.output {
+		s = append(s, fmt.Sprint(v))
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// machine is the registers a compiled program runs on.
+type machine struct {
+	A, B, C int
+	out     []int
+	// err is set by an instruction that can't run, which then returns
+	// failed.
+	err error
+}
+
+// failed is what an instruction returns, instead of the index of the
+// next one, when it sets err.
+const failed = -1
+
+// instruction runs one compiled instruction, and returns the index of
+// the next one to run.
+type instruction func(m *machine) int
+
+// compiled is a program turned into a closure per instruction, for
+// running it many times over, e.g. when searching for an A. Everything
+// that execute works out at each step, like what kind of operand an
+// instruction reads or which register goes where, is settled once here.
+type compiled struct {
+	code []instruction
+	// limit is like computer's.
+	limit int
+	m     machine
+}
+
+// compile turns program into closures. It doesn't fail: an instruction
+// that can't run compiles to one that returns its error when reached,
+// as execute would.
+func compile(program []operation) *compiled {
+	p := &compiled{code: make([]instruction, len(program))}
+	for i, op := range program {
+		p.code[i] = compileOne(i, op, len(program))
+	}
+	return p
+}
+
+func compileOne(i int, op operation, n int) instruction {
+	ip, next := 2*i, i+1
+	fail := func(err error) instruction {
+		return func(m *machine) int {
+			m.err = err
+			return failed
+		}
+	}
+	if op.opcode < adv || op.opcode >= invalidOpcode || op.operand < lit0 || op.operand >= invalidOperand {
+		return fail(fmt.Errorf("ip %d: %d,%d: %w", ip, int(op.opcode), int(op.operand), ErrInvalidOpcode))
+	}
+
+	switch op.opcode {
+	case bxl:
+		k := int(op.operand)
+		return func(m *machine) int { m.B ^= k; return next }
+	case bxc:
+		return func(m *machine) int { m.B ^= m.C; return next }
+	case jnz:
+		t := int(op.operand)
+		switch {
+		case t%2 != 0:
+			return func(m *machine) int {
+				if m.A == 0 {
+					return next
+				}
+				m.err = fmt.Errorf("ip %d: %w", t, ErrBadJump)
+				return failed
+			}
+		}
+		// A jump past the end ends the program.
+		to := min(t/2, n)
+		return func(m *machine) int {
+			if m.A == 0 {
+				return next
+			}
+			return to
+		}
+	}
+
+	// The rest read a combo operand.
+	if op.operand == halt {
+		return fail(fmt.Errorf("ip %d: %v: %w", ip, op.text(), ErrReservedOperand))
+	}
+	// Each reading of each register gets a closure of its own, rather
+	// than calling another to read it.
+	k := int(op.operand)
+	switch op.opcode {
+	case bst:
+		switch op.operand {
+		case regA:
+			return func(m *machine) int { m.B = m.A % 8; return next }
+		case regB:
+			return func(m *machine) int { m.B = m.B % 8; return next }
+		case regC:
+			return func(m *machine) int { m.B = m.C % 8; return next }
+		}
+		return func(m *machine) int { m.B = k; return next }
+	case out:
+		switch op.operand {
+		case regA:
+			return func(m *machine) int { m.out = append(m.out, m.A%8); return next }
+		case regB:
+			return func(m *machine) int { m.out = append(m.out, m.B%8); return next }
+		case regC:
+			return func(m *machine) int { m.out = append(m.out, m.C%8); return next }
+		}
+		return func(m *machine) int { m.out = append(m.out, k); return next }
+	case adv:
+		switch op.operand {
+		case regA:
+			return func(m *machine) int { m.A = shiftDiv(m.A, m.A); return next }
+		case regB:
+			return func(m *machine) int { m.A = shiftDiv(m.A, m.B); return next }
+		case regC:
+			return func(m *machine) int { m.A = shiftDiv(m.A, m.C); return next }
+		}
+		return func(m *machine) int { m.A = shiftDiv(m.A, k); return next }
+	case bdv:
+		switch op.operand {
+		case regA:
+			return func(m *machine) int { m.B = shiftDiv(m.A, m.A); return next }
+		case regB:
+			return func(m *machine) int { m.B = shiftDiv(m.A, m.B); return next }
+		case regC:
+			return func(m *machine) int { m.B = shiftDiv(m.A, m.C); return next }
+		}
+		return func(m *machine) int { m.B = shiftDiv(m.A, k); return next }
+	}
+	// cdv
+	switch op.operand {
+	case regA:
+		return func(m *machine) int { m.C = shiftDiv(m.A, m.A); return next }
+	case regB:
+		return func(m *machine) int { m.C = shiftDiv(m.A, m.B); return next }
+	case regC:
+		return func(m *machine) int { m.C = shiftDiv(m.A, m.C); return next }
+	}
+	return func(m *machine) int { m.C = shiftDiv(m.A, k); return next }
+}
+
+// shiftDiv is x / 2^n, rounded towards 0 as execute's division rounds.
+// It's small enough to be inlined.
+func shiftDiv(x, n int) int {
+	if x >= 0 && uint(n) < 63 {
+		return x >> n
+	}
+	return slowDiv(x, n)
+}
+
+func slowDiv(x, n int) int {
+	if x < 0 || n < 0 {
+		// Only from negative registers, which puzzle input can't have;
+		// done just as execute does it, so as to agree on the edge cases.
+		return int(float64(x) / math.Pow(2, float64(n)))
+	}
+	return 0
+}
+
+// run runs the program from the given registers, and returns its output
+// and what execute would have returned. The output is only good until
+// the next run.
+//
+// Loops are looked for at the jumps back, which every loop goes through,
+// rather than at every step.
+func (p *compiled) run(a, b, c int) ([]int, error) {
+	p.m = machine{A: a, B: b, C: c, out: p.m.out[:0]}
+	m := &p.m
+	var loops loopDetector[state]
+	steps := 0
+	for i := 0; i < len(p.code); {
+		steps++
+		if p.limit > 0 && steps > p.limit {
+			return m.out, errStepLimit
+		}
+		next := p.code[i](m)
+		if next == failed {
+			return m.out, m.err
+		}
+		if next <= i && loops.repeats(state{2 * next, m.A, m.B, m.C}) {
+			return m.out, fmt.Errorf("ip %d, A=%d B=%d C=%d again: %w", 2*next, m.A, m.B, m.C, ErrLoop)
+		}
+		i = next
+	}
+	return m.out, nil
+}
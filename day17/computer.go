@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"math/big"
 	"strconv"
 	"strings"
 )
@@ -83,7 +84,7 @@ type operation struct {
 }
 
 func (o operation) String() string {
-	return fmt.Sprintf("[%v %v]", o.opcode, o.operand)
+	return "[" + o.text() + "]"
 }
 
 type computer struct {
@@ -99,6 +100,21 @@ type computer struct {
 	halt bool
 	// The program's output
 	output []int
+	// verbose logs every instruction as it runs.
+	verbose bool
+	// tracing records every instruction into trace, for replaying or
+	// comparing runs.
+	tracing bool
+	trace   trace
+	// limit, if > 0, is how many instructions execute runs before giving
+	// up with errStepLimit, in case the program never halts.
+	limit int
+}
+
+func (c *computer) logf(format string, v ...any) {
+	if c.verbose {
+		log.Printf(format, v...)
+	}
 }
 
 func (c *computer) String() string {
@@ -128,36 +144,42 @@ func initComputer(a, b, c int, program []operation) *computer {
 
 var errHalt = errors.New("HALTED")
 var errNotImpl = errors.New("TODO")
+var errStepLimit = errors.New("step limit reached")
 
-func (c *computer) nextOperation() (opcode, operand, bool) {
-	if c.ip == 2*len(c.program) {
-		return invalidOpcode, invalidOperand, true
+// nextOperation fetches the instruction at ip; end is true if ip has run
+// off the end of the program, including by a jump past it.
+func (c *computer) nextOperation() (opc opcode, opa operand, end bool, err error) {
+	if c.ip < 0 || c.ip%2 != 0 {
+		return invalidOpcode, invalidOperand, false, fmt.Errorf("ip %d: %w", c.ip, ErrBadJump)
+	}
+	if c.ip >= 2*len(c.program) {
+		return invalidOpcode, invalidOperand, true, nil
 	}
 	op := c.program[c.ip/2]
-	//log.Printf("Next operation, at ip=%d: %v", c.ip, op)
-	return op.opcode, op.operand, false
-
+	if op.opcode < adv || op.opcode >= invalidOpcode || op.operand < lit0 || op.operand >= invalidOperand {
+		return invalidOpcode, invalidOperand, false, fmt.Errorf("ip %d: %d,%d: %w", c.ip, int(op.opcode), int(op.operand), ErrInvalidOpcode)
+	}
+	return op.opcode, op.operand, false, nil
 }
 
-func (c *computer) eval(opa operand) int {
+func (c *computer) eval(opa operand) (int, error) {
 	switch opa {
 	case lit0:
-		return 0
+		return 0, nil
 	case lit1:
-		return 1
+		return 1, nil
 	case lit2:
-		return 2
+		return 2, nil
 	case lit3:
-		return 3
+		return 3, nil
 	case regA:
-		return c.A
+		return c.A, nil
 	case regB:
-		return c.B
+		return c.B, nil
 	case regC:
-		return c.C
+		return c.C, nil
 	}
-	log.Fatalf("Unknown operand %v", opa)
-	return 0
+	return 0, ErrReservedOperand
 }
 
 func intPow(a, b int) int {
@@ -166,70 +188,109 @@ func intPow(a, b int) int {
 
 func (c *computer) execute() error {
 	count := 0
+	var loops loopDetector[state]
 	for {
 		count++
 		//log.Printf("\n\n------------- Starting op #%d --------------\n", count)
-		if c.halt {
-			return errHalt
+		if c.limit > 0 && count > c.limit {
+			return errStepLimit
 		}
-		opc, opa, end := c.nextOperation()
-		if end {
-			break
+		if loops.repeats(c.state()) {
+			return fmt.Errorf("ip %d, A=%d B=%d C=%d again: %w", c.ip, c.A, c.B, c.C, ErrLoop)
 		}
-		incIp := true
-
-		div := func(num int, opa operand) int {
-			return int(float64(num) / math.Pow(2.0, float64(c.eval(opa))))
+		done, err := c.step()
+		if err != nil {
+			return err
 		}
+		if done {
+			return nil
+		}
+		//log.Printf("Computer state:\n%v", c.String())
+	}
+}
 
-		log.Printf(">> %v %v", opc, opa)
+// step runs the one instruction at ip. done is true, and the computer
+// halts, when ip has run off the end of the program instead.
+func (c *computer) step() (done bool, err error) {
+	if c.halt {
+		return false, errHalt
+	}
+	opc, opa, end, err := c.nextOperation()
+	if err != nil {
+		return false, err
+	}
+	if end {
+		c.halt = true
+		return true, nil
+	}
+	incIp := true
 
-		switch opc {
-		case adv:
-			r := div(c.A, opa)
-			log.Printf("A/2^%s -> %d/%d -> %d -> A", opa, c.A, intPow(2, c.eval(opa)), r)
-			c.A = r
-		case bxl:
-			r := c.B ^ int(opa)
-			log.Printf("B^%s -> %d^%d -> %d -> B", opa, c.B, int(opa), r)
-			c.B = r
-		case bst:
-			r := c.eval(opa) % 8
-			log.Printf("%s %%8 -> %d %%8 -> %d -> B", opa, c.eval(opa), r)
-			c.B = r
-		case jnz:
-			if c.A == 0 {
-				// does nothing
-				log.Printf("A==0 -> no jump")
-			} else {
-				log.Printf("jump %d", int(opa))
-				c.ip = int(opa)
-				incIp = false
-			}
-		case bxc:
-			// operand is ignored
-			r := c.B ^ c.C
-			log.Printf("B^C -> %d^%d -> %d -> B", c.B, c.C, r)
-			c.B = r
-		case out:
-			r := c.eval(opa) % 8
-			log.Printf("out %s %%8 -> %d %%8 -> %d out", opa, c.eval(opa), r)
-			c.output = append(c.output, r)
-		case bdv:
-			r := div(c.A, opa)
-			log.Printf("A/2^%s -> %d/%d -> %d -> B", opa, c.A, intPow(2, c.eval(opa)), r)
-			c.B = r
-		case cdv:
-			r := div(c.A, opa)
-			log.Printf("A/2^%s -> %d/%d -> %d -> C", opa, c.A, intPow(2, c.eval(opa)), r)
-			c.C = r
+	// v is the operand's value: the literal, or what the combo operand
+	// reads.
+	v := int(opa)
+	if opc.operandKind() == comboOperand {
+		if v, err = c.eval(opa); err != nil {
+			return false, fmt.Errorf("ip %d: %v: %w", c.ip, operation{opc, opa}.text(), err)
 		}
-		if incIp {
-			c.ip += 2
+	}
+	div := func(num int) int {
+		return int(float64(num) / math.Pow(2.0, float64(v)))
+	}
+
+	c.logf(">> %v %v", opc, opa)
+	var te traceEntry
+	outLen := len(c.output)
+	if c.tracing {
+		te = c.traceBefore(opc, opa, v)
+	}
+
+	switch opc {
+	case adv:
+		r := div(c.A)
+		c.logf("A/2^%s -> %d/%d -> %d -> A", opa, c.A, intPow(2, v), r)
+		c.A = r
+	case bxl:
+		r := c.B ^ int(opa)
+		c.logf("B^%s -> %d^%d -> %d -> B", opa, c.B, int(opa), r)
+		c.B = r
+	case bst:
+		r := v % 8
+		c.logf("%s %%8 -> %d %%8 -> %d -> B", opa, v, r)
+		c.B = r
+	case jnz:
+		if c.A == 0 {
+			// does nothing
+			c.logf("A==0 -> no jump")
+		} else {
+			c.logf("jump %d", int(opa))
+			c.ip = int(opa)
+			incIp = false
 		}
-		//log.Printf("Computer state:\n%v", c.String())
+	case bxc:
+		// operand is ignored
+		r := c.B ^ c.C
+		c.logf("B^C -> %d^%d -> %d -> B", c.B, c.C, r)
+		c.B = r
+	case out:
+		r := v % 8
+		c.logf("out %s %%8 -> %d %%8 -> %d out", opa, v, r)
+		c.output = append(c.output, r)
+	case bdv:
+		r := div(c.A)
+		c.logf("A/2^%s -> %d/%d -> %d -> B", opa, c.A, intPow(2, v), r)
+		c.B = r
+	case cdv:
+		r := div(c.A)
+		c.logf("A/2^%s -> %d/%d -> %d -> C", opa, c.A, intPow(2, v), r)
+		c.C = r
+	}
+	if incIp {
+		c.ip += 2
+	}
+	if c.tracing {
+		c.traceAfter(te, outLen)
 	}
-	return nil
+	return false, nil
 }
 
 func (c *computer) out() string {
@@ -243,26 +304,123 @@ func (c *computer) out() string {
 	return b.String()
 }
 
+// parseInput reads the puzzle input:
+//
+//	Register A: 729
+//	Register B: 0
+//	Register C: 0
+//
+//	Program: 0,1,5,4,3,0
+//
+// Blank lines don't matter, nor does the order, but each register and
+// the program have to be there once. Registers too big for an int are an
+// error; parseBigInput takes them.
 func parseInput(in []string) (*computer, error) {
-	if len(in) != 5 {
-		return nil, fmt.Errorf("input: got %d lines want 5", len(in))
+	p, err := readInput(in)
+	if err != nil {
+		return nil, err
+	}
+	var regs [3]int
+	for i, v := range p.regs {
+		if !v.IsInt64() {
+			return nil, parseErrorf(p.lines[i], "register %c: %v is too big", 'A'+i, v)
+		}
+		regs[i] = int(v.Int64())
+	}
+	return initComputer(regs[0], regs[1], regs[2], p.program), nil
+}
+
+// puzzle is the puzzle input, with registers of any size.
+type puzzle struct {
+	// regs are A, B and C, and lines where they were.
+	regs    [3]*big.Int
+	lines   [3]int
+	program []operation
+}
+
+func readInput(in []string) (*puzzle, error) {
+	var r puzzle
+	programLine := 0
+	for n, line := range in {
+		n++
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(line, ":")
+		val = strings.TrimSpace(val)
+		switch {
+		case !ok:
+			return nil, parseErrorf(n, "want \"Register X: N\" or \"Program: ...\", got %q", line)
+		case key == "Register A" || key == "Register B" || key == "Register C":
+			i := key[len(key)-1] - 'A'
+			if r.regs[i] != nil {
+				return nil, parseErrorf(n, "register %c given twice", 'A'+i)
+			}
+			v, ok := new(big.Int).SetString(val, 10)
+			if !ok || v.Sign() < 0 {
+				return nil, parseErrorf(n, "register %c: %q isn't a whole number", 'A'+i, val)
+			}
+			r.regs[i], r.lines[i] = v, n
+		case key == "Program":
+			if programLine > 0 {
+				return nil, parseErrorf(n, "program given twice")
+			}
+			var err error
+			if r.program, err = parseProgram(val); err != nil {
+				return nil, &ErrParse{n, err}
+			}
+			programLine = n
+		default:
+			return nil, parseErrorf(n, "unknown %q", key)
+		}
+	}
+	for i, v := range r.regs {
+		if v == nil {
+			return nil, parseErrorf(len(in), "no register %c", 'A'+i)
+		}
 	}
-	f1 := func(s string) int { return int(mustParseInt(s[(strings.Index(s, ":") + 2):len(s)])) }
-	a, b, c := f1(in[0]), f1(in[1]), f1(in[2])
-	if len(in[3]) != 0 {
-		return nil, fmt.Errorf("input: got non-empty line3 (%d chars) want 0", len(in[3]))
+	if programLine == 0 {
+		return nil, parseErrorf(len(in), "no program")
 	}
-	progStr := in[4][(strings.Index(in[4], ":") + 2):len(in[4])]
-	bytes := strings.Split(progStr, ",")
+	return &r, nil
+}
+
+// parseProgram reads a comma-separated program, e.g. "0,1,5,4,3,0".
+func parseProgram(s string) ([]operation, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	bytes := strings.Split(s, ",")
 	if len(bytes)%2 != 0 {
-		return nil, fmt.Errorf("program: got %d bytes want even number", len(bytes))
+		return nil, fmt.Errorf("program: got %d values want an even number", len(bytes))
+	}
+	vs := make([]int, len(bytes))
+	for i, b := range bytes {
+		v, err := strconv.Atoi(strings.TrimSpace(b))
+		if err != nil {
+			return nil, fmt.Errorf("program: value %d: %q isn't a number", i+1, b)
+		}
+		if v < 0 || v > 7 {
+			return nil, fmt.Errorf("program: value %d: %d: %w", i+1, v, ErrInvalidOpcode)
+		}
+		vs[i] = v
 	}
 	var program []operation
-	for i := 0; i < len(bytes); i += 2 {
-		program = append(program, operation{
-			opcode:  opcode(int(mustParseInt(bytes[i]))),
-			operand: operand(int(mustParseInt(bytes[i+1]))),
-		})
+	for i := 0; i < len(vs); i += 2 {
+		program = append(program, operation{opcode: opcode(vs[i]), operand: operand(vs[i+1])})
 	}
-	return initComputer(a, b, c, program), nil
+	return program, nil
+}
+
+// formatProgram writes program the way parseProgram reads it.
+func formatProgram(program []operation) string {
+	var s strings.Builder
+	for i, op := range program {
+		if i > 0 {
+			s.WriteRune(',')
+		}
+		s.WriteString(fmt.Sprintf("%d,%d", int(op.opcode), int(op.operand)))
+	}
+	return s.String()
 }
@@ -1,9 +1,17 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
-	"os"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
 )
 
 /* Example input
@@ -14,23 +22,41 @@ Register C: 0
 Program: 0,1,5,4,3,0
 */
 
-func assert(s string, b bool) {
-	if !b {
-		log.Fatalf("boom: %v", s)
-	}
-}
+// example and input are the example program and my puzzle input's.
+const example = `
+	L0:
+	adv 1
+	out A
+	jnz L0
+`
+
+const input = `
+	loop:
+	bst A
+	bxl 2
+	cdv B
+	adv 3
+	bxc 7
+	bxl 7
+	out B
+	jnz loop
+`
 
 func runTests() {
 	// If register C contains 9, the program 2,6 would set register B to 1.
 	{
-		c := initComputer(0, 0, 9, []operation{{2, 6}})
+		c := initComputer(0, 0, 9, mustAssemble("bst C"))
 		err := c.execute()
 		assert(fmt.Sprintf("exec err %v", err), err == nil)
 		assert(fmt.Sprintf("c.B is %d want 1", c.B), c.B == 1)
 	}
 	// If register A contains 10, the program 5,0,5,1,5,4 would output 0,1,2.
 	{
-		c := initComputer(10, 0, 0, []operation{{5, 0}, {5, 1}, {5, 4}})
+		c := initComputer(10, 0, 0, mustAssemble(`
+			out 0
+			out 1
+			out A
+		`))
 		err := c.execute()
 		assert(fmt.Sprintf("exec err %v", err), err == nil)
 		out := c.out()
@@ -39,7 +65,7 @@ func runTests() {
 	// If register A contains 2024, the program 0,1,5,4,3,0 would output
 	// 4,2,5,6,7,7,7,7,3,1,0 and leave 0 in register A
 	{
-		c := initComputer(2024, 0, 0, []operation{{0, 1}, {5, 4}, {3, 0}})
+		c := initComputer(2024, 0, 0, mustAssemble(example))
 		err := c.execute()
 		assert(fmt.Sprintf("exec err %v", err), err == nil)
 		out := c.out()
@@ -48,7 +74,7 @@ func runTests() {
 	}
 	// If register B contains 29, the program 1,7 would set register B to 26.
 	{
-		c := initComputer(0, 29, 0, []operation{{1, 7}})
+		c := initComputer(0, 29, 0, mustAssemble("bxl 7"))
 		err := c.execute()
 		assert(fmt.Sprintf("exec err %v", err), err == nil)
 		assert(fmt.Sprintf("c.B is %d want 26", c.B), c.B == 26)
@@ -56,14 +82,14 @@ func runTests() {
 	// If register B contains 2024 and register C contains 43690, the program
 	// 4,0 would set register B to 44354
 	{
-		c := initComputer(0, 2024, 43690, []operation{{4, 0}})
+		c := initComputer(0, 2024, 43690, mustAssemble("bxc"))
 		err := c.execute()
 		assert(fmt.Sprintf("exec err %v", err), err == nil)
 		assert(fmt.Sprintf("c.B is %d want 44354", c.B), c.B == 44354)
 	}
 	// Example
 	{
-		c := initComputer(729, 0, 0, []operation{{0, 1}, {5, 4}, {3, 0}})
+		c := initComputer(729, 0, 0, mustAssemble(example))
 		err := c.execute()
 		assert(fmt.Sprintf("exec err %v", err), err == nil)
 		out := c.out()
@@ -76,7 +102,7 @@ func runTests() {
 	// Register C: 0
 	// Program: 2,4,1,2,7,5,0,3,4,7,1,7,5,5,3,0
 	{
-		c := initComputer(30878003, 0, 0, []operation{{2, 4}, {1, 2}, {7, 5}, {0, 3}, {4, 7}, {1, 7}, {5, 5}, {3, 0}})
+		c := initComputer(30878003, 0, 0, mustAssemble(input))
 		err := c.execute()
 		assert(fmt.Sprintf("exec err %v", err), err == nil)
 		out := c.out()
@@ -84,30 +110,663 @@ func runTests() {
 		assert(fmt.Sprintf("out is %v want %v", out, want), out == want)
 	}
 
+	runDisassemblerTests()
+}
+
+// runDecompilerTests checks the pseudocode for my input in full, and bits
+// of it for other shapes of program.
+func runDecompilerTests() {
+	got := decompile(mustAssemble(input))
+	want := `do {
+    B = A % 8
+    B ^= 2
+    C = A >> B
+    A >>= 3
+    B ^= C
+    B ^= 7
+    out(B % 8)
+} while (A != 0)
+// Each time round, with A as it was at the top:
+//   prints ((A % 8) ^ (A >> ((A % 8) ^ 2)) ^ 5) % 8, which depends only on A's low 10 bits (A % 1024)
+//   A >>= 3, dropping the octal digit just used
+//   B and C are set before they're read, so only A carries over
+`
+	assert(fmt.Sprintf("decompiled input:\n%s\nwant:\n%s", got, want), got == want)
+
+	for _, tc := range []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"example", example, []string{"    A >>= 1\n    out(A % 8)\n", "prints (A >> 1) % 8, which depends only on A's low 4 bits (A % 16)", "//   A >>= 1\n"}},
+		{"set up first", "bst 3\nL: out B\nbdv 2\nadv B\njnz L", []string{
+			"B = 3\ndo {\n    out(B % 8)\n",
+			"prints B % 8, which doesn't depend on A",
+			"//   A = A >> (A >> 2)",
+			"B from the time before also matter",
+		}},
+		{"carries B and C", "L: bxc\nout B\nadv 3\njnz L", []string{"prints (B ^ C) % 8", "B and C from the time before"}},
+		{"no loop", "out 3\nbxl 1", []string{"out(3)\nB ^= 1\n"}},
+		{"gotos", "L0: jnz L4\nout 1\nL4: out 2\njnz L0", []string{"L0:\nif (A != 0) goto L4\nout(1)\nL4:\nout(2)\nif (A != 0) goto L0\n"}},
+		{"reserved", "cdv ?7", []string{"error(\"cdv reads reserved combo operand 7\")"}},
+		{"A never changes", "L: out A\njnz L", []string{"A doesn't change"}},
+	} {
+		got := decompile(mustAssemble(tc.src))
+		for _, w := range tc.want {
+			assert(fmt.Sprintf("%s: want %q in:\n%s", tc.name, w, got), strings.Contains(got, w))
+		}
+	}
+
+	// Operands past 7 and jumps before the start are shown as errors,
+	// rather than panicking.
+	got = decompile([]operation{{adv, 8}, {jnz, 0}})
+	want = "do {\n    error(\"invalid instruction 0,8\")\n} while (A != 0)\n"
+	assert(fmt.Sprintf("decompiled adv 8:\n%s\nwant:\n%s", got, want), got == want)
+	got = decompile([]operation{{out, regB}, {jnz, -2}})
+	want = "out(B % 8)\nerror(\"invalid instruction 3,-2\")\n"
+	assert(fmt.Sprintf("decompiled jnz -2:\n%s\nwant:\n%s", got, want), got == want)
+}
+
+// runCompileTests runs programs both compiled and interpreted, and checks
+// they print the same, end up the same, and fail the same way: every
+// program of two instructions, and n random ones of up to 16.
+func runCompileTests(n int, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	kinds := []error{ErrReservedOperand, ErrBadJump, ErrInvalidOpcode, ErrLoop, errStepLimit}
+	same := func(program []operation, a, b, c int) {
+		cpu := initComputer(a, b, c, program)
+		err := cpu.execute()
+		p := compile(program)
+		out, perr := p.run(a, b, c)
+		what := fmt.Sprintf("%s with A=%d B=%d C=%d", formatProgram(program), a, b, c)
+		for _, k := range kinds {
+			assert(fmt.Sprintf("%s: interpreted err %v, compiled %v", what, err, perr), errors.Is(err, k) == errors.Is(perr, k))
+		}
+		assert(fmt.Sprintf("%s: interpreted err %v, compiled %v", what, err, perr), (err == nil) == (perr == nil))
+		assert(fmt.Sprintf("%s: interpreted printed %v, compiled %v", what, cpu.output, out), err != nil || equal(cpu.output, out))
+		assert(fmt.Sprintf("%s: interpreted ended A=%d B=%d C=%d, compiled %+v", what, cpu.A, cpu.B, cpu.C, p.m), err != nil || (cpu.A == p.m.A && cpu.B == p.m.B && cpu.C == p.m.C))
+	}
+	for i := 0; i < 64*64; i++ {
+		program := []operation{{opcode(i / 512), operand(i / 64 % 8)}, {opcode(i / 8 % 8), operand(i % 8)}}
+		for _, a := range []int{0, 1, 8, 2024, -2024, 1 << 50} {
+			same(program, a, 7, 1<<20)
+		}
+	}
+	for i := 0; i < n; i++ {
+		program := make([]operation, 1+rng.Intn(16))
+		for j := range program {
+			program[j] = operation{opcode(rng.Intn(8)), operand(rng.Intn(8))}
+		}
+		// Below 2^53, where execute's division is exact.
+		r := func() int { return rng.Intn(1<<53) >> rng.Intn(53) }
+		same(program, r(), r(), r())
+	}
+	same(mustAssemble(input), 190384113204239, 0, 0)
+}
+
+// runBigTests shows computer's float64 division going wrong past 2^53,
+// and checks bigComputer against the exact shifts of compiled programs,
+// and on numbers too big for either.
+func runBigTests() {
+	bigRun := func(program []operation, a *big.Int) *bigComputer {
+		c := initBigComputer(a, new(big.Int), new(big.Int), program)
+		err := c.execute()
+		assert(fmt.Sprintf("big %s with A=%v: err %v", formatProgram(program), a, err), err == nil)
+		return c
+	}
+	// A / 2^0 loses A's low bit, as float64 can't hold 2^53+1.
+	{
+		a := 1<<53 + 1
+		c := initComputer(a, 0, 0, mustAssemble("adv 0"))
+		c.execute()
+		b := bigRun(mustAssemble("adv 0"), big.NewInt(int64(a)))
+		assert(fmt.Sprintf("float division gave %d, expected it to be off", c.A), c.A == 1<<53)
+		assert(fmt.Sprintf("big division gave %v want %d", b.A, a), b.A.Int64() == int64(a))
+	}
+	// Octal 77777777777777777777 rounds up to 2^60 as a float64, so the
+	// first digit printed is 0 rather than 7.
+	{
+		a := 1<<60 - 1
+		c := initComputer(a, 0, 0, mustAssemble(example))
+		c.execute()
+		b := bigRun(mustAssemble(example), big.NewInt(int64(a)))
+		out, err := compile(mustAssemble(example)).run(a, 0, 0)
+		assert(fmt.Sprintf("compiled err %v", err), err == nil)
+		assert(fmt.Sprintf("big printed %s, compiled %v", b.out(), out), equal(b.output, out))
+		assert(fmt.Sprintf("float division printed the same as exact: %s", c.out()), c.out() != b.out())
+		assert(fmt.Sprintf("big printed %s", b.out()), strings.HasPrefix(b.out(), "7,7,7,"))
+	}
+	// Below 2^53 they all agree.
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		a := rng.Intn(1 << exactBits)
+		c := initComputer(a, 0, 0, mustAssemble(input))
+		c.execute()
+		b := bigRun(mustAssemble(input), big.NewInt(int64(a)))
+		assert(fmt.Sprintf("A=%d: small printed %s, big %s", a, c.out(), b.out()), c.out() == b.out())
+	}
+	// Past 64 bits: example2's program prints A's octal digits from the
+	// second lowest up, then 0.
+	{
+		digits := make([]byte, 100)
+		digits[0] = '1' + byte(rng.Intn(7))
+		for i := 1; i < len(digits); i++ {
+			digits[i] = '0' + byte(rng.Intn(8))
+		}
+		a, _ := new(big.Int).SetString(string(digits), 8)
+		var want []string
+		for i := len(digits) - 2; i >= 0; i-- {
+			want = append(want, string(digits[i]))
+		}
+		want = append(want, "0")
+		b := bigRun(mustAssemble("L0: adv 3\nout A\njnz L0"), a)
+		assert(fmt.Sprintf("A=%s (octal): printed %s", digits, b.out()), b.out() == strings.Join(want, ","))
+	}
+
+	// Inputs pick the computer to suit.
+	in := func(a string) []string {
+		return []string{"Register A: " + a, "Register B: 0", "Register C: 0", "", "Program: 0,3,5,4,3,0"}
+	}
+	for _, tc := range []struct {
+		a        string
+		forceBig bool
+		big      bool
+	}{
+		{"2024", false, false},
+		{"2024", true, true},
+		{strconv.Itoa(1<<exactBits - 1), false, false},
+		{strconv.Itoa(1 << exactBits), false, true},
+		{"1234567890123456789012345678901234567890", false, true},
+	} {
+		r, err := loadComputer(in(tc.a), tc.forceBig)
+		_, isBig := r.(*bigComputer)
+		assert(fmt.Sprintf("A=%s -big=%t: err %v, big %t want %t", tc.a, tc.forceBig, err, isBig, tc.big), err == nil && isBig == tc.big)
+	}
+	_, err := parseInput(in("1234567890123456789012345678901234567890"))
+	var pe *ErrParse
+	assert(fmt.Sprintf("parseInput of a huge A: err %v", err), errors.As(err, &pe) && pe.Line == 1)
+}
+
+// runErrorTests checks that bad input and bad programs come back as the
+// right errors.
+func runErrorTests() {
+	for _, tc := range []struct {
+		name string
+		in   string
+		line int
+		is   error
+	}{
+		{"shuffled", "Program: 0,3,5,4,3,0\n\nRegister C: 0\n  Register A: 2024  \n\nRegister B: 0", 0, nil},
+		{"no register", "Register A: 1\nRegister B: 0\n\nProgram: 0,3", 4, nil},
+		{"no program", "Register A: 1\nRegister B: 0\nRegister C: 0\n", 4, nil},
+		{"register twice", "Register A: 1\nRegister A: 1\n", 2, nil},
+		{"not a number", "Register A: lots\n", 1, nil},
+		{"negative", "Register A: -1\n", 1, nil},
+		{"junk", "Register A: 1\nhello\n", 2, nil},
+		{"unknown", "Register D: 1\n", 1, nil},
+		{"odd program", "Register A: 1\nRegister B: 0\nRegister C: 0\n\nProgram: 0,3,5", 5, nil},
+		{"opcode 9", "Register A: 1\nRegister B: 0\nRegister C: 0\n\nProgram: 9,3", 5, ErrInvalidOpcode},
+		{"operand 8", "Register A: 1\nRegister B: 0\nRegister C: 0\n\nProgram: 0,8", 5, ErrInvalidOpcode},
+	} {
+		c, err := parseInput(strings.Split(tc.in, "\n"))
+		if tc.line == 0 {
+			assert(fmt.Sprintf("%s: err %v", tc.name, err), err == nil && c.A == 2024 && c.out() == "" && formatProgram(c.program) == "0,3,5,4,3,0")
+			continue
+		}
+		var pe *ErrParse
+		assert(fmt.Sprintf("%s: err %v want an ErrParse", tc.name, err), errors.As(err, &pe))
+		assert(fmt.Sprintf("%s: %v: line %d want %d", tc.name, err, pe.Line, tc.line), pe.Line == tc.line)
+		assert(fmt.Sprintf("%s: %v isn't %v", tc.name, err, tc.is), tc.is == nil || errors.Is(err, tc.is))
+	}
+	_, err := assemble("bxc\n\nmul 2")
+	var pe *ErrParse
+	assert(fmt.Sprintf("assemble err %v want an ErrParse on line 3", err), errors.As(err, &pe) && pe.Line == 3)
+
+	for _, tc := range []struct {
+		name    string
+		program []operation
+		a       int
+		want    error
+	}{
+		{"combo 7", mustAssemble("bst ?7"), 0, ErrReservedOperand},
+		{"bxl 7 is fine", mustAssemble("bxl 7"), 0, nil},
+		{"odd jump", mustAssemble("jnz 3"), 1, ErrBadJump},
+		{"jump past the end halts", mustAssemble("jnz 6"), 1, nil},
+		{"opcode 9", []operation{{9, 0}}, 0, ErrInvalidOpcode},
+		{"operand -1", []operation{{bxl, -1}}, 0, ErrInvalidOpcode},
+		{"tight loop", mustAssemble("L: jnz L"), 1, ErrLoop},
+		// B cycles through 1, 0, 1, ... with A never reaching 0.
+		{"longer loop", mustAssemble("L: bxl 1\nout B\nadv 0\njnz L"), 5, ErrLoop},
+		{"halving down to 0", mustAssemble("L: adv 1\njnz L"), 1 << 40, nil},
+	} {
+		c := initComputer(tc.a, 0, 0, tc.program)
+		err := c.execute()
+		assert(fmt.Sprintf("%s: err %v want %v", tc.name, err, tc.want), errors.Is(err, tc.want))
+	}
+}
+
+// runFuzzTests throws n random programs, inputs, assembly and debugger
+// scripts at the computer and everything around it, and checks that
+// nothing panics: every problem has to come back as an error.
+func runFuzzTests(n int, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	safely := func(what string, f func()) {
+		defer func() {
+			if r := recover(); r != nil {
+				assert(fmt.Sprintf("%s: panicked: %v", what, r), false)
+			}
+		}()
+		f()
+	}
+	known := func(err error) bool {
+		for _, e := range []error{ErrReservedOperand, ErrBadJump, ErrInvalidOpcode, ErrLoop, errStepLimit} {
+			if errors.Is(err, e) {
+				return true
+			}
+		}
+		return err == nil
+	}
+	runIt := func(program []operation, a, b, c int) {
+		what := fmt.Sprintf("A=%d B=%d C=%d %v", a, b, c, program)
+		safely(what, func() {
+			cpu := initComputer(a, b, c, program)
+			cpu.limit = quineSteps
+			cpu.tracing = rng.Intn(2) == 0
+			err := cpu.execute()
+			assert(fmt.Sprintf("%s: err %v", what, err), known(err))
+			disassemble(program)
+			decompile(program)
+			compile(program).run(a, b, c)
+			bc := initBigComputer(big.NewInt(int64(a)), big.NewInt(int64(b)), big.NewInt(int64(c)), program)
+			bc.limit = quineSteps
+			err = bc.execute()
+			assert(fmt.Sprintf("%s: big err %v", what, err), known(err))
+		})
+	}
+	register := func() int {
+		switch rng.Intn(4) {
+		case 0:
+			return rng.Intn(16)
+		case 1:
+			return rng.Int()
+		case 2:
+			return -rng.Intn(1000)
+		}
+		return rng.Intn(1 << 20)
+	}
+	threeBits := func() int {
+		if rng.Intn(20) == 0 {
+			return rng.Intn(12) - 2
+		}
+		return rng.Intn(8)
+	}
+
+	// Every program of two instructions.
+	for i := 0; i < 64*64; i++ {
+		program := []operation{{opcode(i / 512), operand(i / 64 % 8)}, {opcode(i / 8 % 8), operand(i % 8)}}
+		for _, a := range []int{0, 1, 8, 2024, 1 << 50} {
+			runIt(program, a, 0, 0)
+		}
+	}
+	// Random ones, some with numbers that aren't 3 bits.
+	for i := 0; i < n; i++ {
+		program := make([]operation, 1+rng.Intn(12))
+		for j := range program {
+			program[j] = operation{opcode(threeBits()), operand(threeBits())}
+		}
+		runIt(program, register(), register(), register())
+	}
+
+	// Mangled puzzle input and assembly have to parse or fail with an
+	// ErrParse.
+	mangle := func(s string) string {
+		const junk = "0123456789,:;# -\nABCRegistrPogamadvbxljnzout?L"
+		b := []byte(s)
+		for k := 1 + rng.Intn(4); k > 0; k-- {
+			i := rng.Intn(len(b) + 1)
+			switch rng.Intn(3) {
+			case 0:
+				if i < len(b) {
+					b = append(b[:i], b[i+1:]...)
+				}
+			case 1:
+				b = append(b[:i], append([]byte{junk[rng.Intn(len(junk))]}, b[i:]...)...)
+			default:
+				if i < len(b) {
+					b[i] = junk[rng.Intn(len(junk))]
+				}
+			}
+		}
+		return string(b)
+	}
+	puzzle := "Register A: 729\nRegister B: 0\nRegister C: 0\n\nProgram: 0,1,5,4,3,0"
+	for i := 0; i < n; i++ {
+		in := mangle(puzzle)
+		safely(fmt.Sprintf("parseInput(%q)", in), func() {
+			c, err := parseInput(strings.Split(in, "\n"))
+			var pe *ErrParse
+			assert(fmt.Sprintf("parseInput(%q): err %v", in, err), err == nil || errors.As(err, &pe))
+			if err == nil {
+				runIt(c.program, c.A, c.B, c.C)
+			}
+		})
+		src := mangle(input)
+		safely(fmt.Sprintf("assemble(%q)", src), func() {
+			program, err := assemble(src)
+			var pe *ErrParse
+			assert(fmt.Sprintf("assemble(%q): err %v", src, err), err == nil || errors.As(err, &pe))
+			if err == nil {
+				runIt(program, register(), register(), register())
+			}
+		})
+	}
+
+	// Random debugger commands.
+	commands := []string{"s", "step", "back", "c", "b", "w", "set", "r", "o", "l", "reset", "x", ""}
+	args := []string{"", "1", "3", "-2", "A", "ip", "A=5", "ip=3", "ip=-4", "ip=100", "B=-1", "C=" + strconv.Itoa(rng.Int())}
+	for i := 0; i < n/10; i++ {
+		var script strings.Builder
+		for k := rng.Intn(20); k > 0; k-- {
+			script.WriteString(commands[rng.Intn(len(commands))] + " " + args[rng.Intn(len(args))] + "\n")
+		}
+		safely(fmt.Sprintf("debugger script %q", script.String()), func() {
+			d := newDebugger(initComputer(2024, 0, 0, mustAssemble(input)), strings.NewReader(script.String()), io.Discard)
+			d.run()
+		})
+	}
+}
+
+func runDisassemblerTests() {
+	for _, tc := range []struct {
+		op   operation
+		want string
+	}{
+		// Literal operands are numbers, even where the combo reading would
+		// be a register or reserved.
+		{operation{bxl, 7}, "[bxl 7]"},
+		{operation{jnz, 0}, "[jnz 0]"},
+		{operation{bst, 4}, "[bst A]"},
+		{operation{cdv, 5}, "[cdv B]"},
+		{operation{adv, 3}, "[adv 3]"},
+		{operation{bxc, 0}, "[bxc]"},
+		{operation{bxc, 6}, "[bxc 6]"},
+		{operation{out, 7}, "[out ?7]"},
+	} {
+		got := tc.op.String()
+		assert(fmt.Sprintf("%d,%d shows as %s want %s", tc.op.opcode, tc.op.operand, got, tc.want), got == tc.want)
+	}
+
+	got := disassemble(mustAssemble(input))
+	want := `L0:
+   0  bst A      ; B = A % 8
+   2  bxl 2      ; B = B ^ 2
+   4  cdv B      ; C = A >> B
+   6  adv 3      ; A = A >> 3
+   8  bxc 7      ; B = B ^ C
+  10  bxl 7      ; B = B ^ 7
+  12  out B      ; out B % 8
+  14  jnz L0     ; if A != 0 goto 0
+`
+	assert(fmt.Sprintf("disassembled:\n%s\nwant:\n%s", got, want), got == want)
+
+	got = disassemble([]operation{{3, 3}, {3, 8}, {6, 7}})
+	want = `   0  jnz 3      ; if A != 0 goto 3, mid-instruction
+   2  jnz 8      ; if A != 0 goto 8, past the end, so halts
+   4  bdv ?7     ; B = A >> ?7, but combo operand 7 is reserved
+`
+	assert(fmt.Sprintf("disassembled:\n%s\nwant:\n%s", got, want), got == want)
+
+	runAssemblerTests()
+	runDebuggerTests()
+	runTraceTests()
+	runDecompilerTests()
+	runErrorTests()
+	runCompileTests(2000, 1)
+	runBigTests()
+	runFuzzTests(2000, 1)
+}
+
+// runDebuggerTests drives the debugger with scripts, and checks for lines
+// it should have printed, in order.
+func runDebuggerTests() {
+	for _, tc := range []struct {
+		name   string
+		a      int
+		script string
+		want   []string
+	}{
+		{"step and regs", 2024, "step\nregs\nstep 2\nout\n", []string{
+			"   0  adv 3      ; A 2024 -> 253",
+			"A: 253  bin 11111101  oct 375",
+			"ip: 2  steps: 1",
+			"   2  out A      ; out 5",
+			"   4  jnz 0      ; jump to 0",
+			"output: 5",
+		}},
+		{"break and continue", 2024, "break 4\nc\nc\nout\nc\nc\nc\nc\n", []string{
+			"breakpoint at 4",
+			"=>    4  jnz 0",
+			"breakpoint at 4",
+			"output: 5,7",
+			"halted after 12 steps, output 5,7,3,0",
+			"the program has halted",
+		}},
+		{"watch, set and reset", 2024, "watch A\nc\nset A=8\nc\nc\nreset\nc\n", []string{
+			"watch A: 2024 -> 253 at 0",
+			"A: 8  bin 1000  oct 10",
+			"watch A: 8 -> 1 at 0",
+			"watch A: 1 -> 0 at 0",
+			"=>    0  adv 3",
+			"watch A: 2024 -> 253 at 0",
+		}},
+		{"bad commands", 2024, "break 3\nwatch D\nset A\nfly\n", []string{
+			"break: \"3\" isn't the address of an instruction",
+			"watch: want A, B or C",
+			"set: want A=N",
+			"unknown command \"fly\"",
+		}},
+		{"back", 2024, "step 3\nback\nregs\nback 2\nregs\nout\nback\nstep 4\nout\n", []string{
+			"undid #3    4  jnz 0      A=253 B=0 C=0 -> 0",
+			"=>    4  jnz 0",
+			"ip: 4  steps: 2",
+			"undid #2    2  out A      A=253 B=0 C=0 out 5",
+			"undid #1    0  adv 3      A=253 B=0 C=0",
+			"A: 2024  bin",
+			"ip: 0  steps: 0",
+			"output: \n",
+			"back at the start",
+			"output: 5\n",
+		}},
+		{"back from halted", 2024, "c\nback\nout\nc\n", []string{
+			"halted after 12 steps, output 5,7,3,0",
+			"undid #12",
+			"output: 5,7,3,0",
+			"halted after 12 steps, output 5,7,3,0",
+		}},
+		{"never halts", 1, "c\n", []string{"stopped: the program loops forever, back at ip 0 with A=1"}},
+		{"bad ip", 2024, "set ip=-2\ns\nset ip=3\nc\nl\n", []string{
+			"ip -2: ip isn't at an instruction",
+			"=>   -2  isn't an instruction",
+			"ip 3: ip isn't at an instruction",
+			"=>    3  isn't an instruction",
+		}},
+		{"empty program", 2024, "l\ns\nc\nout\n", []string{
+			"the program is empty",
+			"halted after 0 steps, output \n",
+			"the program has halted",
+		}},
+	} {
+		// The program from example2.
+		program := mustAssemble("L0: adv 3\nout A\njnz L0")
+		switch tc.name {
+		case "never halts":
+			program = mustAssemble("loop: jnz loop")
+		case "empty program":
+			program = nil
+		}
+		var out strings.Builder
+		newDebugger(initComputer(tc.a, 0, 0, program), strings.NewReader(tc.script), &out).run()
+		rest := out.String()
+		for _, w := range tc.want {
+			i := strings.Index(rest, w)
+			assert(fmt.Sprintf("%s: want %q in:\n%s", tc.name, w, out.String()), i >= 0)
+			rest = rest[i+len(w):]
+		}
+	}
+}
+
+// runTraceTests checks what traces record, that they write out, and how
+// two are compared.
+func runTraceTests() {
+	program := mustAssemble("L0: adv 3\nout A\njnz L0")
+	t, err := traced(program, 2024, 0, 0)
+	assert(fmt.Sprintf("traced err %v", err), err == nil)
+	assert(fmt.Sprintf("trace has %d steps want 12", len(t)), len(t) == 12)
+	want := traceEntry{Step: 2, IP: 2, Op: "out", Operand: regA, Value: 253,
+		Before: registers{253, 0, 0}, After: registers{253, 0, 0}, NextIP: 4, Out: 5}
+	assert(fmt.Sprintf("step 2 is %+v want %+v", t[1], want), t[1] == want)
+	want = traceEntry{Step: 3, IP: 4, Op: "jnz", Operand: 0, Value: 0,
+		Before: registers{253, 0, 0}, After: registers{253, 0, 0}, NextIP: 0, Out: -1}
+	assert(fmt.Sprintf("step 3 is %+v want %+v", t[2], want), t[2] == want)
+	assert(fmt.Sprintf("last step is %+v", t[11]), t[11].NextIP == 6 && t[11].After.A == 0)
+
+	var csv strings.Builder
+	err = t[:2].write(&csv, "csv")
+	assert(fmt.Sprintf("csv err %v", err), err == nil)
+	wantCSV := "step,ip,op,operand,value,a_before,b_before,c_before,a_after,b_after,c_after,next_ip,out\n" +
+		"1,0,adv,3,3,2024,0,0,253,0,0,2,-1\n" +
+		"2,2,out,4,253,253,0,0,253,0,0,4,5\n"
+	assert(fmt.Sprintf("csv is:\n%s", csv.String()), csv.String() == wantCSV)
+	var js strings.Builder
+	err = t[:1].write(&js, "json")
+	assert(fmt.Sprintf("json err %v", err), err == nil)
+	var back trace
+	err = json.Unmarshal([]byte(js.String()), &back)
+	assert(fmt.Sprintf("json %s: err %v", js.String(), err), err == nil && len(back) == 1 && back[0] == t[0])
+	assert("bad format should fail", t.write(&js, "xml") != nil)
+
+	// Runs diverge at the first different output or jump. A=2024 is octal
+	// 3750, which prints 5,7,3,0.
+	for _, tc := range []struct {
+		a, at int
+		want  string
+	}{
+		{2024, -1, "traces run the same way, 12 steps"},
+		// 3760 prints 6 first.
+		{2032, 1, "differs in: value 253 vs 254, A before 253 vs 254, A 253 vs 254, out 5 vs 6"},
+		// 13750 prints a 1 before the 0.
+		{2024 + 8*8*8*8, 10, "2>  #11    2  out A      A=1 B=0 C=0 out 1"},
+		// 750 stops a loop early, printing 0 where 3 was.
+		{2024 % (8 * 8 * 8), 7, "out 3 vs 0"},
+	} {
+		other, err := traced(program, tc.a, 0, 0)
+		assert(fmt.Sprintf("traced(%d) err %v", tc.a, err), err == nil)
+		at, report := diffTraces(t, other, 2)
+		assert(fmt.Sprintf("A=%d: diverged at %d want %d:\n%s", tc.a, at, tc.at, report), at == tc.at)
+		assert(fmt.Sprintf("A=%d: want %q in:\n%s", tc.a, tc.want, report), strings.Contains(report, tc.want))
+	}
+	at, report := diffTraces(t, t[:5], 2)
+	assert(fmt.Sprintf("cut short: diverged at %d want 5:\n%s", at, report), at == 5 && strings.Contains(report, "first 12 steps, second 5"))
+}
+
+func runAssemblerTests() {
+	// Listings assemble back to the programs they came from.
+	var every []operation
+	for opc := adv; opc < invalidOpcode; opc++ {
+		for opa := lit0; opa < invalidOperand; opa++ {
+			every = append(every, operation{opcode(opc), operand(opa)})
+		}
+	}
+	for _, raw := range []string{"0,1,5,4,3,0", "0,3,5,4,3,0", "2,4,1,2,7,5,0,3,4,7,1,7,5,5,3,0", "3,2,3,3,3,6,5,4", formatProgram(every)} {
+		program, err := parseProgram(raw)
+		assert(fmt.Sprintf("parseProgram(%s) err %v", raw, err), err == nil)
+		listing := disassemble(program)
+		back, err := assemble(listing)
+		assert(fmt.Sprintf("assembling listing of %s: %v\n%s", raw, err, listing), err == nil)
+		assert(fmt.Sprintf("%s came back as %s from:\n%s", raw, formatProgram(back), listing), formatProgram(back) == raw)
+	}
+
+	// Source assembles to the same program as its listing does.
+	src := `
+		; Prints A's octal digits, lowest first.
+		start:  out A   # same as bst A; out B
+		        ADV 3
+		        jnz start
+		        bxc
+	`
+	program, err := assemble(src)
+	assert(fmt.Sprintf("assemble err %v", err), err == nil)
+	assert(fmt.Sprintf("assembled %s", formatProgram(program)), formatProgram(program) == "5,4,0,3,3,0,4,0")
+	again, err := assemble(disassemble(program))
+	assert(fmt.Sprintf("reassemble err %v", err), err == nil)
+	assert(fmt.Sprintf("reassembled %s", formatProgram(again)), formatProgram(again) == formatProgram(program))
+
+	for _, bad := range []string{
+		"adv",                                   // missing operand
+		"adv 4",                                 // combo 4 has to be written A
+		"bxl A",                                 // literal operand
+		"bxl 8",                                 // too big for 3 bits
+		"out A B",                               // too many operands
+		"mul 2",                                 // no such instruction
+		"jnz nowhere",                           // undefined label
+		"x: bxc\nx: bxc",                        // duplicate label
+		"2 adv 1",                               // wrong address
+		"bxc\nbxc\nbxc\nbxc\nfar: bxc\njnz far", // label out of reach
+	} {
+		_, err := assemble(bad)
+		assert(fmt.Sprintf("assemble(%q) should fail", bad), err != nil)
+	}
 }
 
 func main() {
 	log.Println("AoC-2024-day17-part1")
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: main <in file>")
+	verbose := flag.Bool("v", false, "log every instruction as it runs")
+	tracePath := flag.String("trace", "", "write a trace of the run to this file, as JSON if it ends .json and CSV otherwise")
+	fuzz := flag.Int("fuzz", 0, "also run this many more random programs and inputs, looking for panics")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed for -fuzz")
+	useBig := flag.Bool("big", false, "use big registers even if the input's would fit in 53 bits")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: main [-v] [-trace file.csv|file.json] [-big] [-fuzz N [-seed N]] <in file>")
 	}
-	lines, err := readLines(os.Args[1])
+	lines, err := readLines(flag.Arg(0))
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
 
 	runTests()
+	if *fuzz > 0 {
+		log.Printf("Fuzzing with %d random cases, seed %d", *fuzz, *seed)
+		runFuzzTests(*fuzz, *seed)
+	}
 
 	log.Printf("Input: %v", lines)
-	c, err := parseInput(lines)
+	r, err := loadComputer(lines, *useBig)
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
 
-	log.Printf("Computer initial state: %v", c)
-
-	if err = c.execute(); err != nil {
+	c, small := r.(*computer)
+	if small {
+		log.Printf("Computer initial state: %v", c)
+		c.verbose = *verbose
+		c.tracing = *tracePath != ""
+	} else {
+		log.Printf("Using big registers, as asked or as a register has over %d bits", exactBits)
+		if *tracePath != "" {
+			// Traces record registers as ints.
+			log.Fatal("-trace needs registers that fit in 53 bits, and doesn't go with -big")
+		}
+		r.(*bigComputer).verbose = *verbose
+	}
+	if err = r.execute(); err != nil {
 		log.Fatalf("Error: %v", err)
 	}
-	log.Printf("Execution complete; output=%v", c.out())
+	if small && c.tracing {
+		if err := c.trace.save(*tracePath); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		log.Printf("Wrote %d steps to %s", len(c.trace), *tracePath)
+	}
+	log.Printf("Execution complete; output=%v", r.out())
 }
@@ -1,103 +1,305 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"log"
-	"os"
+	"math/big"
+	"math/rand"
+	"slices"
 	"strconv"
-	"strings"
+	"testing"
 )
 
 /* Example input
-Register A: 729
+Register A: 2024
 Register B: 0
 Register C: 0
 
-Program: 0,1,5,4,3,0
+Program: 0,3,5,4,3,0
 
 but a glitch changes the initial register A value
 find the new A that causes program to output itself.
 */
 
-func convert(s string) []int {
-	part1 := strings.Split(s, ":")[1]
-	seq := part1[1:len(part1)]
-	bits := strings.Split(seq, ",")
-	var out []int
-	for _, b := range bits {
-		out = append(out, int(mustParseInt(b)))
+func runTests() {
+	for _, tc := range []struct {
+		name    string
+		program string
+		found   bool
+		want    int
+	}{
+		{"example2", "0,3,5,4,3,0", true, 117440},
+		{"my input", "2,4,1,2,7,5,0,3,4,7,1,7,5,5,3,0", true, 190384113204239},
+		// Always prints A % 8 then 0, so can never print 5.
+		{"no solution", "5,4,5,0", false, 0},
+		// Never halts, so never finishes printing anything.
+		{"no halt", "1,1,3,0", false, 0},
+	} {
+		program, err := parseProgram(tc.program)
+		assert(fmt.Sprintf("%s: parseProgram err %v", tc.name, err), err == nil)
+		a, found, _ := findQuine(program, 0, 0)
+		assert(fmt.Sprintf("%s: found %t want %t", tc.name, found, tc.found), found == tc.found)
+		assert(fmt.Sprintf("%s: A=%d want %d", tc.name, a, tc.want), a == tc.want)
+		if found {
+			out, ok := run(program, a, 0, 0)
+			assert(fmt.Sprintf("%s: A=%d prints %v", tc.name, a, out), ok && equal(out, values(program)))
+		}
 	}
-	return out
+
+	runSolverTests()
 }
 
-func permute(in []int) []int {
-	perms := map[int]int{
-		5: 0,
-		4: 1,
-		// 5: 2,
-		7: 3,
-		1: 4,
-		0: 5,
-		3: 6,
-		2: 7,
+// runSolverTests checks solve against findQuine, part 1, and a brute-force
+// search over small As.
+func runSolverTests() {
+	for _, tc := range []struct {
+		name    string
+		program string
+		target  string
+		limit   int
+		// want are the first solutions, total how many there are, and
+		// has one of them, when not 0.
+		want  []int
+		total int
+		has   int
+	}{
+		// A's lowest octal digit is shifted out before anything's printed.
+		{"example2 quine", "0,3,5,4,3,0", "0,3,5,4,3,0", 0,
+			[]int{117440, 117441, 117442, 117443, 117444, 117445, 117446, 117447}, 8, 0},
+		{"my quine", "2,4,1,2,7,5,0,3,4,7,1,7,5,5,3,0", "2,4,1,2,7,5,0,3,4,7,1,7,5,5,3,0", 3,
+			[]int{190384113204239, 190384113269775, 190384113355697}, 22, 0},
+		// My A isn't the only one that prints my part 1 answer.
+		{"my part 1", "2,4,1,2,7,5,0,3,4,7,1,7,5,5,3,0", "7,1,3,7,5,1,0,3,4", 0, []int{24421928}, 16, 30878003},
+		// Shifting A 1 bit per output needs Bits.
+		{"the example's output", "0,1,5,4,3,0", "4,6,3,5,6,3,5,2,1,0", 0, nil, 0, 729},
+		// Always prints 0 second.
+		{"can't print 4", "5,4,5,0", "5,4", 0, nil, 0, 0},
+		{"prints A % 8", "5,4,5,0", "5,0", 0, []int{5, 13, 21, 29, 37, 45, 53, 61}, 8, 0},
+	} {
+		program, err := parseProgram(tc.program)
+		assert(fmt.Sprintf("%s: parseProgram err %v", tc.name, err), err == nil)
+		target, err := parseOutput(tc.target)
+		assert(fmt.Sprintf("%s: parseOutput err %v", tc.name, err), err == nil)
+		res, err := solve(program, 0, 0, target, solveOptions{Limit: tc.limit})
+		assert(fmt.Sprintf("%s: solve err %v", tc.name, err), err == nil)
+		if tc.want != nil {
+			as := res.as()
+			assert(fmt.Sprintf("%s: solutions %v want %v", tc.name, as, tc.want), equal(as[:min(len(as), len(tc.want))], tc.want))
+		}
+		if tc.has != 0 {
+			assert(fmt.Sprintf("%s: solutions %v should have %d", tc.name, res.as(), tc.has), slices.Contains(res.as(), tc.has))
+		}
+		if tc.want == nil && tc.has == 0 {
+			assert(fmt.Sprintf("%s: solutions %v want none", tc.name, res.as()), res.Total == 0)
+		}
+		if tc.total > 0 {
+			assert(fmt.Sprintf("%s: %d solutions want %d", tc.name, res.Total, tc.total), res.Total == tc.total)
+		}
+		for _, a := range res.as() {
+			out, ok := run(program, a, 0, 0)
+			assert(fmt.Sprintf("%s: A=%d prints %v", tc.name, a, out), ok && equal(out, target))
+		}
+		if tc.target == tc.program {
+			a, found, _ := findQuine(program, 0, 0)
+			assert(fmt.Sprintf("%s: findQuine %d, solve %v", tc.name, a, res.as()), found && res.Total > 0 && res.Solutions[0].A == a)
+		}
 	}
 
-	var out []int
-	for _, v := range in {
-		out = append(out, perms[v])
+	// Every A under 8^5 that prints something of 5 digits, found by trying
+	// them all, is found by solve too.
+	program := mustAssemble("L: bst A\nbxl 3\ncdv B\nbxc\nadv 3\nout B\njnz L")
+	byTarget := map[string][]int{}
+	for a := 0; a < 8*8*8*8*8; a++ {
+		out, ok := run(program, a, 0, 0)
+		if ok && len(out) == 5 {
+			byTarget[fmt.Sprint(out)] = append(byTarget[fmt.Sprint(out)], a)
+		}
+	}
+	checked := 0
+	for _, as := range byTarget {
+		out, _ := run(program, as[0], 0, 0)
+		res, err := solve(program, 0, 0, out, solveOptions{})
+		assert(fmt.Sprintf("%v: solve err %v", out, err), err == nil)
+		assert(fmt.Sprintf("%v: solve found %v, trying them all %v", out, res.as(), as), equal(res.as(), as))
+		if checked++; checked == 200 {
+			break
+		}
 	}
-	return out
+
+	runUnknownBCTests()
+
+	_, err := solve(program, 0, 0, []int{8}, solveOptions{})
+	assert("solve for an 8 should fail", err != nil)
+	_, err = solve(mustAssemble("out 1"), 0, 0, []int{1}, solveOptions{Bits: 40})
+	assert(fmt.Sprintf("solve with A's bits ignored: err %v", err), errors.Is(err, errTooManyNodes))
 }
 
-func seed(seq []int) int {
-	s := 0
-	if len(seq) > 0 {
-		for idx := len(seq) - 1; idx >= 0; idx-- {
-			v := seq[idx]
-			s += v * 8
-			s *= 8
+// runUnknownBCTests checks solving for B and C as well as A.
+func runUnknownBCTests() {
+	// each is every A under 8 with b and c.
+	each := func(b, c int, anyB, anyC bool) []solution {
+		var ss []solution
+		for a := 0; a < 8; a++ {
+			ss = append(ss, solution{a, b, c, anyB, anyC})
+		}
+		return ss
+	}
+	var quine []solution
+	for a := 117440; a <= 117447; a++ {
+		quine = append(quine, solution{A: a, AnyB: true, AnyC: true})
+	}
+	unknown := solveOptions{UnknownB: true, UnknownC: true}
+	for _, tc := range []struct {
+		name    string
+		program string
+		b       int
+		target  string
+		o       solveOptions
+		want    []solution
+	}{
+		// A's never read, and only 3 bits of B are.
+		{"prints B", "5,5", 0, "5", unknown, each(5, 0, false, true)},
+		// B and C aren't read, so can be anything.
+		{"example2 quine", "0,3,5,4,3,0", 0, "0,3,5,4,3,0", unknown, quine},
+		// -8 % 8 is 0, negative or not.
+		{"negative B", "5,5", -8, "0", solveOptions{}, each(-8, 0, false, false)},
+	} {
+		program, err := parseProgram(tc.program)
+		assert(fmt.Sprintf("%s: parseProgram err %v", tc.name, err), err == nil)
+		target, err := parseOutput(tc.target)
+		assert(fmt.Sprintf("%s: parseOutput err %v", tc.name, err), err == nil)
+		res, err := solve(program, tc.b, 0, target, tc.o)
+		assert(fmt.Sprintf("%s: solve err %v", tc.name, err), err == nil)
+		assert(fmt.Sprintf("%s: solutions %v want %v", tc.name, res.Solutions, tc.want), slices.Equal(res.Solutions, tc.want) && res.Total == len(tc.want))
+	}
+
+	// Every A and C under 8^3 that print something of 3 digits, found by
+	// trying them all, are found by solve too. B is set before it's read.
+	program := mustAssemble("L: bst A\nbxc\nout B\nadv 3\njnz L")
+	byTarget := map[string][]solution{}
+	for a := 0; a < 8*8*8; a++ {
+		for c := 0; c < 8*8*8; c++ {
+			out, ok := run(program, a, 0, c)
+			if ok && len(out) == 3 {
+				byTarget[fmt.Sprint(out)] = append(byTarget[fmt.Sprint(out)], solution{A: a, C: c, AnyB: true})
+			}
+		}
+	}
+	checked := 0
+	for _, ss := range byTarget {
+		out, _ := run(program, ss[0].A, 0, ss[0].C)
+		res, err := solve(program, 0, 0, out, solveOptions{Bits: 9, UnknownB: true, UnknownC: true})
+		assert(fmt.Sprintf("%v: solve err %v", out, err), err == nil)
+		assert(fmt.Sprintf("%v: solve found %d solutions, trying them all %d", out, res.Total, len(ss)), slices.Equal(res.Solutions, ss))
+		if checked++; checked == 20 {
+			break
 		}
 	}
-	return s / 8
+}
+
+// runBenchmarks times running my input's program interpreted and
+// compiled, over the same As, as a brute-force search would.
+func runBenchmarks() {
+	program, _ := parseProgram("2,4,1,2,7,5,0,3,4,7,1,7,5,5,3,0")
+	rng := rand.New(rand.NewSource(1))
+	as := make([]int, 1024)
+	for i := range as {
+		// Up to 16 octal digits, as long as the answer's.
+		as[i] = rng.Intn(1 << 48)
+	}
+	p := compile(program)
+	var results []testing.BenchmarkResult
+	for _, b := range []struct {
+		name string
+		run  func(a int)
+	}{
+		{"interpreted", func(a int) { run(program, a, 0, 0) }},
+		{"compiled", func(a int) { p.run(a, 0, 0) }},
+	} {
+		res := testing.Benchmark(func(tb *testing.B) {
+			for i := 0; i < tb.N; i++ {
+				b.run(as[i%len(as)])
+			}
+		})
+		log.Printf("  %-12s %v", b.name, res)
+		results = append(results, res)
+	}
+	log.Printf("Compiled runs are %.1fx as fast", float64(results[0].NsPerOp())/float64(results[1].NsPerOp()))
 }
 
 func main() {
 	log.Println("AoC-2024-day17-part2")
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: main <in file>")
+	tryA := flag.String("a", "", "just run the program with this A, of any size, and show what it prints")
+	bench := flag.Bool("bench", false, "time interpreted against compiled runs, instead")
+	symbolic := flag.Bool("symbolic", false, "solve for A symbolically, finding every solution rather than the lowest")
+	unknownB := flag.Bool("unknown-b", false, "with -symbolic, solve for B too rather than taking it from the input")
+	unknownC := flag.Bool("unknown-c", false, "with -symbolic, solve for C too rather than taking it from the input")
+	target := flag.String("target", "", "with -symbolic, solve for this output, e.g. 4,6,3, rather than the program")
+	limit := flag.Int("limit", 10, "with -symbolic, show at most this many solutions")
+	bitsWide := flag.Int("bits", 0, "with -symbolic, how wide A, and B and C if unknown, can be; default 3 bits per output")
+	flag.Parse()
+	if *bench {
+		runBenchmarks()
+		return
+	}
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: main [-a N] [-bench] [-symbolic [-unknown-b] [-unknown-c] [-target 1,2,3] [-limit N] [-bits N]] <in file>")
 	}
-	lines, err := readLines(os.Args[1])
+	lines, err := readLines(flag.Arg(0))
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
-	input := convert(lines[len(lines)-1])
 
-	var glitchedA int
-	if len(os.Args) == 3 {
-		log.Printf("Overriding glitchedA: %s", os.Args[2])
-		glitchedA = int(mustParseInt(os.Args[2]))
-	} else {
-		glitchedA = seed(permute(input))
-		log.Printf("Calculating glitchedA: %d", glitchedA)
-	}
-
-	log.Printf("\nInput: %v\nGlitched A: %d\nA binary: %v", lines, glitchedA, strconv.FormatInt(int64(glitchedA), 2))
+	runTests()
 
 	c, err := parseInput(lines)
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
+	want := values(c.program)
 
-	c.A = glitchedA
-
-	log.Printf("Computer initial state: %v", c)
-
-	if err = c.execute(); err != nil {
-		log.Fatalf("Error: %v", err)
+	if *tryA != "" {
+		// Big registers, so that As past 2^53 come out right too.
+		a, ok := new(big.Int).SetString(*tryA, 10)
+		if !ok || a.Sign() < 0 {
+			log.Fatalf("-a %q isn't a whole number", *tryA)
+		}
+		cpu := initBigComputer(a, big.NewInt(int64(c.B)), big.NewInt(int64(c.C)), c.program)
+		cpu.limit = quineSteps
+		if err := cpu.execute(); err != nil {
+			log.Fatalf("A=%v: %v", a, err)
+		}
+		log.Printf("A=%v (octal %s) prints %v; quine: %t", a, a.Text(8), cpu.output, equal(cpu.output, want))
+		return
 	}
 
-	log.Printf("Computer final state: %v", c)
-
-	output := c.out()
-	log.Printf("%d: Execution complete; output=%v\ninput=%v", glitchedA, output, input)
+	if *symbolic {
+		if *target != "" {
+			if want, err = parseOutput(*target); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+		}
+		o := solveOptions{Bits: *bitsWide, Limit: *limit, UnknownB: *unknownB, UnknownC: *unknownC}
+		res, err := solve(c.program, c.B, c.C, want, o)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		log.Printf("%d solutions print %v (%d partial registers tried)", res.Total, want, res.Nodes)
+		for _, s := range res.Solutions {
+			log.Printf("  %v", s)
+		}
+		if res.Total > len(res.Solutions) {
+			log.Printf("  and %d more", res.Total-len(res.Solutions))
+		}
+		return
+	}
 
+	a, found, runs := findQuine(c.program, c.B, c.C)
+	if !found {
+		log.Fatalf("No A makes the program print itself (%d runs tried)", runs)
+	}
+	log.Printf("Found after %d runs; A in octal: %s", runs, strconv.FormatInt(int64(a), 8))
+	log.Printf("Lowest A that prints the program: %d", a)
 }
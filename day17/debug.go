@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+)
+
+// debug steps through a day 17 program, taking commands from stdin:
+//
+//	go run debug.go debugger.go tracing.go errors.go assembler.go computer.go listing.go quine.go compile.go util.go <in file>
+func main() {
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: debug <in file>")
+	}
+	lines, err := readLines(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	c, err := parseInput(lines)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	newDebugger(c, os.Stdin, os.Stdout).run()
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// debugger steps a computer under control of commands read from in, a line
+// at a time, so it can be driven by a person or a script.
+type debugger struct {
+	c *computer
+	// initial is what reset goes back to.
+	initial computer
+	in      *bufio.Scanner
+	out     io.Writer
+	breaks  map[int]bool
+	watches map[string]bool
+	// count is how many instructions have run since the last reset.
+	count int
+}
+
+// continueLimit stops continue running on and on, should a program's loop
+// take too long to find.
+const continueLimit = 1000000
+
+func newDebugger(c *computer, in io.Reader, out io.Writer) *debugger {
+	c.tracing = true
+	d := &debugger{
+		c:       c,
+		initial: *c,
+		in:      bufio.NewScanner(in),
+		out:     out,
+		breaks:  map[int]bool{},
+		watches: map[string]bool{},
+	}
+	d.initial.program = append([]operation(nil), c.program...)
+	d.initial.output = nil
+	return d
+}
+
+func (d *debugger) reg(name string) *int {
+	switch strings.ToUpper(name) {
+	case "A":
+		return &d.c.A
+	case "B":
+		return &d.c.B
+	case "C":
+		return &d.c.C
+	case "IP":
+		return &d.c.ip
+	}
+	return nil
+}
+
+// where shows the instruction about to run.
+func (d *debugger) where() string {
+	if d.c.halt || d.c.ip >= 2*len(d.c.program) {
+		return "halted"
+	}
+	if d.c.ip < 0 || d.c.ip%2 != 0 {
+		return fmt.Sprintf("=> %4d  isn't an instruction", d.c.ip)
+	}
+	op := d.c.program[d.c.ip/2]
+	return fmt.Sprintf("=> %4d  %-10s ; %s", d.c.ip, op.text(), op.meaning())
+}
+
+// stepOnce runs one instruction, and reports whether a watched register
+// changed or the program halted, either of which stops a continue. If
+// verbose, it shows the instruction and what it changed.
+func (d *debugger) stepOnce(verbose bool) (stop bool) {
+	before := map[string]int{}
+	for _, r := range []string{"A", "B", "C"} {
+		before[r] = *d.reg(r)
+	}
+	ip, outLen := d.c.ip, len(d.c.output)
+	var op operation
+	if ip >= 0 && ip < 2*len(d.c.program) {
+		op = d.c.program[ip/2]
+	}
+	done, err := d.c.step()
+	if err == errHalt {
+		fmt.Fprintln(d.out, "the program has halted: reset to run it again")
+		return true
+	}
+	if err != nil {
+		fmt.Fprintf(d.out, "%v\n", err)
+		return true
+	}
+	if done {
+		fmt.Fprintf(d.out, "halted after %d steps, output %s\n", d.count, d.c.out())
+		return true
+	}
+	d.count++
+	var changes []string
+	for _, r := range []string{"A", "B", "C"} {
+		if old := before[r]; old != *d.reg(r) {
+			changes = append(changes, fmt.Sprintf("%s %d -> %d", r, old, *d.reg(r)))
+		}
+	}
+	if len(d.c.output) > outLen {
+		changes = append(changes, fmt.Sprintf("out %d", d.c.output[outLen]))
+	}
+	if op.opcode == jnz && d.c.ip != ip+2 {
+		changes = append(changes, fmt.Sprintf("jump to %d", d.c.ip))
+	}
+	if verbose {
+		fmt.Fprintf(d.out, "%4d  %-10s ; %s\n", ip, op.text(), strings.Join(changes, ", "))
+	}
+	for _, w := range []string{"A", "B", "C"} {
+		if d.watches[w] && before[w] != *d.reg(w) {
+			fmt.Fprintf(d.out, "watch %s: %d -> %d at %d\n", w, before[w], *d.reg(w), ip)
+			stop = true
+		}
+	}
+	return stop
+}
+
+// back undoes the last n instructions, from the trace, showing each as it
+// goes.
+func (d *debugger) back(n int) {
+	for i := 0; i < n; i++ {
+		e, ok := d.c.undo()
+		if !ok {
+			fmt.Fprintln(d.out, "back at the start")
+			return
+		}
+		d.count--
+		fmt.Fprintf(d.out, "undid %s\n", e)
+	}
+}
+
+func (d *debugger) regs() {
+	for _, r := range []string{"A", "B", "C"} {
+		v := *d.reg(r)
+		fmt.Fprintf(d.out, "%s: %d  bin %s  oct %s\n", r, v, strconv.FormatInt(int64(v), 2), strconv.FormatInt(int64(v), 8))
+	}
+	fmt.Fprintf(d.out, "ip: %d  steps: %d\n", d.c.ip, d.count)
+}
+
+// run reads and runs commands until quit or the end of the input.
+func (d *debugger) run() {
+	fmt.Fprintln(d.out, d.where())
+	for {
+		fmt.Fprint(d.out, "(debug) ")
+		if !d.in.Scan() {
+			fmt.Fprintln(d.out)
+			return
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(d.in.Text()), " ")
+		arg = strings.TrimSpace(arg)
+		switch cmd {
+		case "", "s", "step":
+			n := 1
+			if arg != "" {
+				var err error
+				if n, err = strconv.Atoi(arg); err != nil || n < 1 {
+					fmt.Fprintf(d.out, "step: want a count, got %q\n", arg)
+					continue
+				}
+			}
+			for i := 0; i < n; i++ {
+				if d.stepOnce(true) {
+					break
+				}
+			}
+			fmt.Fprintln(d.out, d.where())
+		case "back":
+			n := 1
+			if arg != "" {
+				var err error
+				if n, err = strconv.Atoi(arg); err != nil || n < 1 {
+					fmt.Fprintf(d.out, "back: want a count, got %q\n", arg)
+					continue
+				}
+			}
+			d.back(n)
+			fmt.Fprintln(d.out, d.where())
+		case "c", "continue":
+			var loops loopDetector[state]
+			for i := 0; ; i++ {
+				if i == continueLimit {
+					fmt.Fprintf(d.out, "still running after %d steps\n", continueLimit)
+					break
+				}
+				if loops.repeats(d.c.state()) {
+					fmt.Fprintf(d.out, "stopped: the program loops forever, back at ip %d with A=%d B=%d C=%d\n", d.c.ip, d.c.A, d.c.B, d.c.C)
+					break
+				}
+				if d.stepOnce(false) {
+					break
+				}
+				if d.breaks[d.c.ip] {
+					fmt.Fprintf(d.out, "breakpoint at %d\n", d.c.ip)
+					break
+				}
+			}
+			fmt.Fprintln(d.out, d.where())
+		case "b", "break":
+			if arg == "" {
+				fmt.Fprintf(d.out, "breakpoints: %v\n", sortedKeys(d.breaks))
+				continue
+			}
+			ip, err := strconv.Atoi(arg)
+			if err != nil || ip < 0 || ip%2 != 0 || ip >= 2*len(d.c.program) {
+				fmt.Fprintf(d.out, "break: %q isn't the address of an instruction\n", arg)
+				continue
+			}
+			d.breaks[ip] = true
+			fmt.Fprintf(d.out, "breakpoint at %d\n", ip)
+		case "w", "watch":
+			if r := d.reg(arg); r == nil || strings.ToUpper(arg) == "IP" {
+				fmt.Fprintf(d.out, "watch: want A, B or C, got %q\n", arg)
+				continue
+			}
+			d.watches[strings.ToUpper(arg)] = true
+			fmt.Fprintf(d.out, "watching %s\n", strings.ToUpper(arg))
+		case "set":
+			name, val, ok := strings.Cut(arg, "=")
+			r := d.reg(strings.TrimSpace(name))
+			v, err := strconv.Atoi(strings.TrimSpace(val))
+			if !ok || r == nil || err != nil {
+				fmt.Fprintf(d.out, "set: want A=N, B=N, C=N or ip=N, got %q\n", arg)
+				continue
+			}
+			*r = v
+			d.regs()
+		case "r", "regs":
+			d.regs()
+		case "o", "out":
+			fmt.Fprintf(d.out, "output: %s\n", d.c.out())
+		case "l", "list":
+			if len(d.c.program) == 0 {
+				fmt.Fprintln(d.out, "the program is empty")
+				continue
+			}
+			for _, l := range strings.Split(strings.TrimRight(disassemble(d.c.program), "\n"), "\n") {
+				mark := "  "
+				if n, err := strconv.Atoi(strings.Fields(l)[0]); err == nil && n == d.c.ip {
+					mark = "=>"
+				}
+				fmt.Fprintf(d.out, "%s %s\n", mark, l)
+			}
+		case "reset":
+			*d.c = d.initial
+			d.c.program = append([]operation(nil), d.initial.program...)
+			d.count = 0
+			fmt.Fprintln(d.out, d.where())
+		case "q", "quit":
+			return
+		default:
+			fmt.Fprintf(d.out, "unknown command %q: try step [N], back [N], continue, break IP, watch A|B|C, set A=N, regs, out, list, reset or quit\n", cmd)
+		}
+	}
+}
+
+func sortedKeys(m map[int]bool) []int {
+	var ks []int
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Ints(ks)
+	return ks
+}
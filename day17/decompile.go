@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+)
+
+// decompile prints a day 17 program as pseudocode:
+//
+//	go run decompile.go pseudocode.go assembler.go tracing.go errors.go computer.go listing.go util.go <in file>
+func main() {
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: decompile <in file>")
+	}
+	lines, err := readLines(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	c, err := parseInput(lines)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	fmt.Printf("// A = %d, B = %d, C = %d\n", c.A, c.B, c.C)
+	fmt.Print(decompile(c.program))
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+)
+
+// disasm prints a day 17 program as an assembly listing:
+//
+//	go run disasm.go assembler.go tracing.go errors.go computer.go listing.go util.go <in file>
+func main() {
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: disasm <in file>")
+	}
+	lines, err := readLines(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	c, err := parseInput(lines)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	fmt.Printf("; Register A: %d\n; Register B: %d\n; Register C: %d\n", c.A, c.B, c.C)
+	fmt.Print(disassemble(c.program))
+}
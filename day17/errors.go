@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors from running a program. They come wrapped with where it went
+// wrong, so check for them with errors.Is.
+var (
+	// ErrReservedOperand is combo operand 7 being used.
+	ErrReservedOperand = errors.New("combo operand 7 is reserved")
+	// ErrBadJump is ip landing somewhere that isn't the start of an
+	// instruction. Running off the end isn't one: that halts.
+	ErrBadJump = errors.New("ip isn't at an instruction")
+	// ErrInvalidOpcode is an opcode, or an operand, that isn't 3 bits.
+	ErrInvalidOpcode = errors.New("not a 3-bit instruction")
+	// ErrLoop is a program coming back round to a state it was in before,
+	// so it would run forever.
+	ErrLoop = errors.New("program loops forever")
+)
+
+// ErrParse is a problem reading puzzle input or assembly, at Line
+// (counting from 1).
+type ErrParse struct {
+	Line int
+	Err  error
+}
+
+func (e *ErrParse) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ErrParse) Unwrap() error {
+	return e.Err
+}
+
+func parseErrorf(line int, format string, v ...any) error {
+	return &ErrParse{line, fmt.Errorf(format, v...)}
+}
+
+// state is everything that decides what a computer does next; output
+// doesn't.
+type state struct {
+	ip, A, B, C int
+}
+
+func (c *computer) state() state {
+	return state{c.ip, c.A, c.B, c.C}
+}
+
+// loopDetector spots a computer going round the same states forever,
+// with Brent's algorithm: it keeps one state to compare each later one
+// with, replacing it each time the count since reaches the next power of
+// two. So a loop is found within about twice the steps it takes to get
+// into it and round it once, in constant space.
+//
+// No instruction leaves a register wider than the widest one started, so
+// there are only so many states, and any program that doesn't halt loops
+// and will be caught.
+type loopDetector[S comparable] struct {
+	saved        S
+	power, steps int
+}
+
+// repeats reports whether s has been seen before.
+func (l *loopDetector[S]) repeats(s S) bool {
+	if l.power == 0 {
+		l.saved, l.power = s, 1
+		return false
+	}
+	if s == l.saved {
+		return true
+	}
+	l.steps++
+	if l.steps == l.power {
+		l.saved, l.power, l.steps = s, 2*l.power, 0
+	}
+	return false
+}
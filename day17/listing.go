@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// operandKind is how an opcode reads its operand.
+type operandKind int
+
+const (
+	literalOperand operandKind = iota
+	comboOperand
+	ignoredOperand
+)
+
+func (o opcode) operandKind() operandKind {
+	switch o {
+	case bxl, jnz:
+		return literalOperand
+	case bxc:
+		return ignoredOperand
+	}
+	return comboOperand
+}
+
+// combo names a combo operand: 0-3 are themselves, 4-6 are registers, and
+// 7 is reserved.
+func (o operand) combo() string {
+	switch o {
+	case regA:
+		return "A"
+	case regB:
+		return "B"
+	case regC:
+		return "C"
+	case halt:
+		return "?7"
+	}
+	return fmt.Sprintf("%d", int(o))
+}
+
+// text is the instruction as it'd be written, e.g. "bxl 7" or "out B",
+// with its operand read the way its opcode reads it.
+func (o operation) text() string {
+	switch o.opcode.operandKind() {
+	case literalOperand:
+		return fmt.Sprintf("%v %d", o.opcode, int(o.operand))
+	case ignoredOperand:
+		// The operand does nothing, but is shown if set so that the
+		// listing assembles back to the same program.
+		if o.operand != 0 {
+			return fmt.Sprintf("%v %d", o.opcode, int(o.operand))
+		}
+		return o.opcode.String()
+	}
+	return fmt.Sprintf("%v %s", o.opcode, o.operand.combo())
+}
+
+// meaning spells out what the instruction does, e.g. "B = B ^ 7".
+func (o operation) meaning() string {
+	x := o.operand.combo()
+	switch o.opcode {
+	case adv:
+		return "A = A >> " + x
+	case bxl:
+		return fmt.Sprintf("B = B ^ %d", int(o.operand))
+	case bst:
+		return "B = " + x + " % 8"
+	case jnz:
+		return fmt.Sprintf("if A != 0 goto %d", int(o.operand))
+	case bxc:
+		return "B = B ^ C"
+	case out:
+		return "out " + x + " % 8"
+	case bdv:
+		return "B = A >> " + x
+	case cdv:
+		return "C = A >> " + x
+	}
+	return "invalid opcode"
+}
+
+func label(addr int) string {
+	return fmt.Sprintf("L%d", addr)
+}
+
+// disassemble lists the program an instruction per line, with its address
+// and what it does. Jump targets get a label, which the jumps use, so the
+// listing reads like assembly:
+//
+//	L0:
+//	   0  adv 1      ; A = A >> 1
+//	   2  out A      ; out A % 8
+//	   4  jnz L0     ; if A != 0 goto 0
+func disassemble(program []operation) string {
+	targets := map[int]bool{}
+	for _, op := range program {
+		if op.opcode == jnz {
+			targets[int(op.operand)] = true
+		}
+	}
+	var s strings.Builder
+	for i, op := range program {
+		addr := 2 * i
+		if targets[addr] {
+			s.WriteString(label(addr) + ":\n")
+		}
+		text, note := op.text(), op.meaning()
+		if op.opcode == jnz {
+			t := int(op.operand)
+			switch {
+			case t%2 == 1:
+				note += ", mid-instruction"
+			case t >= 2*len(program):
+				note += ", past the end, so halts"
+			default:
+				text = fmt.Sprintf("jnz %s", label(t))
+			}
+		}
+		if op.opcode.operandKind() == comboOperand && op.operand == halt {
+			note += ", but combo operand 7 is reserved"
+		}
+		s.WriteString(fmt.Sprintf("%4d  %-10s ; %s\n", addr, text, note))
+	}
+	return s.String()
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// decompile writes program as pseudocode. A program that ends by jumping
+// back, with no other jumps, becomes a loop:
+//
+//	do {
+//	    B = A % 8
+//	    B ^= 2
+//	    C = A >> B
+//	    A >>= 3
+//	    B ^= C
+//	    B ^= 7
+//	    out(B % 8)
+//	} while (A != 0)
+//
+// followed by what one time round the loop works out from A, which for
+// the puzzle programs says which of A's low bits each output depends on.
+// Anything else has its jumps left as gotos.
+func decompile(program []operation) string {
+	var s strings.Builder
+	last := len(program) - 1
+	structured := last >= 0 && program[last].opcode == jnz
+	if structured {
+		t := int(program[last].operand)
+		structured = t >= 0 && t%2 == 0 && t/2 <= last
+		for _, op := range program[:last] {
+			if op.opcode == jnz {
+				structured = false
+			}
+		}
+	}
+	if !structured {
+		targets := map[int]bool{}
+		for _, op := range program {
+			if op.opcode == jnz {
+				targets[int(op.operand)] = true
+			}
+		}
+		for i, op := range program {
+			if targets[2*i] {
+				s.WriteString(label(2*i) + ":\n")
+			}
+			s.WriteString(statement(op) + "\n")
+		}
+		return s.String()
+	}
+
+	top := int(program[last].operand) / 2
+	for _, op := range program[:top] {
+		s.WriteString(statement(op) + "\n")
+	}
+	body := program[top:last]
+	s.WriteString("do {\n")
+	for _, op := range body {
+		s.WriteString("    " + statement(op) + "\n")
+	}
+	s.WriteString("} while (A != 0)\n")
+	s.WriteString(explainLoop(body))
+	return s.String()
+}
+
+// statement is one instruction as pseudocode.
+func statement(op operation) string {
+	if op.opcode < adv || op.opcode >= invalidOpcode || op.operand < lit0 || op.operand >= invalidOperand {
+		return fmt.Sprintf("error(\"invalid instruction %d,%d\")", int(op.opcode), int(op.operand))
+	}
+	x := op.operand.combo()
+	if op.opcode.operandKind() == comboOperand && op.operand == halt {
+		return fmt.Sprintf("error(\"%v reads reserved combo operand 7\")", op.opcode)
+	}
+	switch op.opcode {
+	case adv:
+		return "A >>= " + x
+	case bxl:
+		return fmt.Sprintf("B ^= %d", int(op.operand))
+	case bst:
+		if op.operand <= lit3 {
+			return "B = " + x
+		}
+		return "B = " + x + " % 8"
+	case jnz:
+		t := int(op.operand)
+		if t%2 == 1 {
+			return fmt.Sprintf("if (A != 0) goto %d  // mid-instruction", t)
+		}
+		return "if (A != 0) goto " + label(t)
+	case bxc:
+		return "B ^= C"
+	case out:
+		if op.operand <= lit3 {
+			return "out(" + x + ")"
+		}
+		return "out(" + x + " % 8)"
+	case bdv:
+		return "B = A >> " + x
+	case cdv:
+		return "C = A >> " + x
+	}
+	return fmt.Sprintf("error(\"invalid instruction %d,%d\")", int(op.opcode), int(op.operand))
+}
+
+// expr is a value worked out in one time round a loop, in terms of the
+// registers at the top of it.
+type expr struct {
+	// op is "A", "B" or "C" for a register, "n" for the number n, or
+	// "%" (mod 8), "^" or ">>" applied to args.
+	op   string
+	n    int
+	args []*expr
+}
+
+func num(n int) *expr { return &expr{op: "n", n: n} }
+
+func mod8(x *expr) *expr {
+	if x.op == "n" {
+		return num(x.n % 8)
+	}
+	if x.op == "%" {
+		return x
+	}
+	return &expr{op: "%", args: []*expr{x}}
+}
+
+// xor flattens nested xors, and folds their numbers into one.
+func xor(xs ...*expr) *expr {
+	var args []*expr
+	k := 0
+	for _, x := range xs {
+		switch x.op {
+		case "^":
+			for _, a := range x.args {
+				if a.op == "n" {
+					k ^= a.n
+				} else {
+					args = append(args, a)
+				}
+			}
+		case "n":
+			k ^= x.n
+		default:
+			args = append(args, x)
+		}
+	}
+	if k != 0 || len(args) == 0 {
+		args = append(args, num(k))
+	}
+	if len(args) == 1 {
+		return args[0]
+	}
+	return &expr{op: "^", args: args}
+}
+
+func shr(x, y *expr) *expr {
+	if y.op == "n" && y.n == 0 {
+		return x
+	}
+	if y.op == "n" && x.op == ">>" && x.args[1].op == "n" {
+		return shr(x.args[0], num(x.args[1].n+y.n))
+	}
+	return &expr{op: ">>", args: []*expr{x, y}}
+}
+
+func (e *expr) String() string {
+	sub := func(x *expr) string {
+		if x.op == "n" || len(x.args) == 0 {
+			return x.String()
+		}
+		return "(" + x.String() + ")"
+	}
+	switch e.op {
+	case "n":
+		return fmt.Sprintf("%d", e.n)
+	case "%":
+		return sub(e.args[0]) + " % 8"
+	case "^":
+		var ss []string
+		for _, a := range e.args {
+			ss = append(ss, sub(a))
+		}
+		return strings.Join(ss, " ^ ")
+	case ">>":
+		return sub(e.args[0]) + " >> " + sub(e.args[1])
+	}
+	return e.op
+}
+
+// maxValue is the most e can be, if that's limited.
+func (e *expr) maxValue() (int, bool) {
+	switch e.op {
+	case "n":
+		return e.n, true
+	case "%":
+		return 7, true
+	case "^":
+		most := 0
+		for _, a := range e.args {
+			m, ok := a.maxValue()
+			if !ok {
+				return 0, false
+			}
+			most |= 1<<bits.Len(uint(m)) - 1
+		}
+		return most, true
+	case ">>":
+		return e.args[0].maxValue()
+	}
+	return 0, false
+}
+
+// allBits stands for needing all of A.
+const allBits = 64
+
+// lowBitsOfA is how many of A's low bits it takes to know e's low w bits.
+func (e *expr) lowBitsOfA(w int) int {
+	if w > allBits {
+		w = allBits
+	}
+	switch e.op {
+	case "A":
+		return w
+	case "%":
+		return e.args[0].lowBitsOfA(min(w, 3))
+	case "^":
+		most := 0
+		for _, a := range e.args {
+			most = max(most, a.lowBitsOfA(w))
+		}
+		return most
+	case ">>":
+		m, ok := e.args[1].maxValue()
+		if !ok {
+			return allBits
+		}
+		return max(e.args[0].lowBitsOfA(w+m), e.args[1].lowBitsOfA(bits.Len(uint(m))))
+	}
+	return 0
+}
+
+// reads adds the registers e reads to rs.
+func (e *expr) reads(rs map[string]bool) {
+	if e.op == "A" || e.op == "B" || e.op == "C" {
+		rs[e.op] = true
+	}
+	for _, a := range e.args {
+		a.reads(rs)
+	}
+}
+
+// explainLoop works out what one time round body does, in terms of the
+// registers at the top, and says which bits of A the outputs depend on,
+// and what's carried over to the next time round.
+func explainLoop(body []operation) string {
+	regs := map[string]*expr{"A": {op: "A"}, "B": {op: "B"}, "C": {op: "C"}}
+	var outs []*expr
+	for _, op := range body {
+		if op.opcode < adv || op.opcode >= invalidOpcode || op.operand < lit0 || op.operand >= invalidOperand {
+			return ""
+		}
+		if op.opcode.operandKind() == comboOperand && op.operand == halt {
+			return ""
+		}
+		v := num(int(op.operand))
+		if op.opcode.operandKind() == comboOperand && op.operand >= regA {
+			v = regs[op.operand.combo()]
+		}
+		switch op.opcode {
+		case adv:
+			regs["A"] = shr(regs["A"], v)
+		case bxl:
+			regs["B"] = xor(regs["B"], v)
+		case bst:
+			regs["B"] = mod8(v)
+		case bxc:
+			regs["B"] = xor(regs["B"], regs["C"])
+		case out:
+			outs = append(outs, mod8(v))
+		case bdv:
+			regs["B"] = shr(regs["A"], v)
+		case cdv:
+			regs["C"] = shr(regs["A"], v)
+		}
+	}
+
+	var s strings.Builder
+	s.WriteString("// Each time round, with A as it was at the top:\n")
+	carried := map[string]bool{}
+	for _, o := range outs {
+		s.WriteString("//   prints " + o.String())
+		switch n := o.lowBitsOfA(3); {
+		case n >= allBits:
+			s.WriteString(", which can depend on all of A\n")
+		case n > 0:
+			s.WriteString(fmt.Sprintf(", which depends only on A's low %d bits (A %% %d)\n", n, 1<<n))
+		default:
+			s.WriteString(", which doesn't depend on A\n")
+		}
+		o.reads(carried)
+	}
+	a := regs["A"]
+	switch {
+	case a.op == "A":
+		s.WriteString("//   A doesn't change, so the loop only ends if A starts as 0\n")
+	case a.op == ">>" && a.args[0].op == "A" && a.args[1].op == "n":
+		s.WriteString(fmt.Sprintf("//   A >>= %d", a.args[1].n))
+		if a.args[1].n == 3 {
+			s.WriteString(", dropping the octal digit just used")
+		}
+		s.WriteString("\n")
+	default:
+		s.WriteString("//   A = " + a.String() + "\n")
+	}
+	a.reads(carried)
+	delete(carried, "A")
+	if len(carried) == 0 {
+		s.WriteString("//   B and C are set before they're read, so only A carries over\n")
+	} else {
+		var rs []string
+		for r := range carried {
+			rs = append(rs, r)
+		}
+		sort.Strings(rs)
+		s.WriteString(fmt.Sprintf("//   %s from the time before also matter\n", strings.Join(rs, " and ")))
+	}
+	return s.String()
+}
@@ -0,0 +1,96 @@
+package main
+
+// values flattens program back into the numbers it was read from, which
+// is the output a quine has to produce.
+func values(program []operation) []int {
+	var vs []int
+	for _, op := range program {
+		vs = append(vs, int(op.opcode), int(op.operand))
+	}
+	return vs
+}
+
+// quineSteps caps each run, so a candidate A that loops forever is simply
+// not a solution.
+const quineSteps = 100000
+
+// run executes program from the given registers, and returns its
+// output; ok is false if it didn't halt within quineSteps.
+func run(program []operation, a, b, c int) (output []int, ok bool) {
+	cpu := initComputer(a, b, c, program)
+	cpu.limit = quineSteps
+	if err := cpu.execute(); err != nil {
+		return nil, false
+	}
+	return cpu.output, true
+}
+
+// findQuine looks for the smallest A that makes program output itself,
+// with B and C starting as given.
+//
+// Programs like this one loop, printing something worked out from the low
+// bits of A and then shifting A down 3 bits, until A is 0. So the last
+// value printed depends only on A's top octal digit, the one before on
+// its top two, and so on. That allows building A a digit at a time from
+// the top: for each digit, try 0-7 in turn, keep those whose run prints
+// the right tail of the program, and backtrack if none does. Trying the
+// digits in order means the first A found is the smallest.
+//
+// Every candidate is checked by running the program, compiled to closures
+// as there are a lot of runs, so an A that is returned is always right. A
+// program that doesn't consume A 3 bits per output this way may have
+// solutions this misses; found is false then, as it is when there's no
+// solution at all. runs counts executions.
+func findQuine(program []operation, b, c int) (a int, found bool, runs int) {
+	want := values(program)
+	if len(want) == 0 {
+		return 0, false, 0
+	}
+	p := compile(program)
+	p.limit = quineSteps
+	try := func(a int) ([]int, bool) {
+		runs++
+		out, err := p.run(a, b, c)
+		return out, err == nil
+	}
+	var search func(prefix, depth int) (int, bool)
+	search = func(prefix, depth int) (int, bool) {
+		tail := want[len(want)-depth:]
+		for d := 0; d < 8; d++ {
+			cand := prefix*8 + d
+			if cand == 0 {
+				// A leading zero digit adds nothing, and A=0 is tried
+				// on its own below.
+				continue
+			}
+			out, ok := try(cand)
+			if !ok || !equal(out, tail) {
+				continue
+			}
+			if depth == len(want) {
+				return cand, true
+			}
+			if a, ok := search(cand, depth+1); ok {
+				return a, true
+			}
+		}
+		return 0, false
+	}
+	if out, ok := try(0); ok && equal(out, want) {
+		return 0, true, runs
+	}
+	a, found = search(0, 1)
+	return a, found, runs
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tbits is a value some of whose bits aren't known yet: bit i is known if
+// it's set in known, and is then bit i of val.
+type tbits struct {
+	known, val uint64
+}
+
+const allKnown = ^uint64(0)
+
+func exactly(n uint64) tbits { return tbits{allKnown, n} }
+
+func (x tbits) isKnown() bool { return x.known == allKnown }
+
+func (x tbits) xor(y tbits) tbits {
+	k := x.known & y.known
+	return tbits{k, (x.val ^ y.val) & k}
+}
+
+func (x tbits) mod8() tbits {
+	return tbits{x.known | ^uint64(7), x.val & 7}
+}
+
+// shr shifts by n, which is known; bits shifted in are 0.
+func (x tbits) shr(n uint64) tbits {
+	if n >= 64 {
+		return exactly(0)
+	}
+	return tbits{x.known>>n | ^(allKnown >> n), x.val >> n}
+}
+
+// merge keeps what x and y agree on.
+func (x tbits) merge(y tbits) tbits {
+	k := x.known & y.known &^ (x.val ^ y.val)
+	return tbits{k, x.val & k}
+}
+
+// could reports whether x could be n.
+func (x tbits) could(n uint64) bool {
+	return n&x.known == x.val
+}
+
+// shrBy shifts x by n, which may not be known: the result is what all the
+// shifts n could be agree on.
+func (x tbits) shrBy(n tbits) tbits {
+	if n.isKnown() {
+		return x.shr(n.val)
+	}
+	var r tbits
+	first := true
+	add := func(y tbits) {
+		if first {
+			r, first = y, false
+		} else {
+			r = r.merge(y)
+		}
+	}
+	for s := uint64(0); s < 64; s++ {
+		if n.could(s) {
+			add(x.shr(s))
+		}
+	}
+	// Anything 64 or more shifts everything out.
+	if n.known&^63 != allKnown&^63 || n.val&^63 != 0 {
+		add(exactly(0))
+	}
+	return r
+}
+
+// negative reports whether x could be negative, taken as an int.
+func (x tbits) negative() bool {
+	return x.known>>63 == 0 || x.val>>63 != 0
+}
+
+// zero says whether x is 0: yes, no, or, if it could be either, unknown.
+func (x tbits) zero() (isZero, known bool) {
+	if x.val != 0 {
+		return false, true
+	}
+	return true, x.isKnown()
+}
+
+// Solver limits.
+const (
+	// solveSteps caps one run of the program on partly known registers.
+	solveSteps = 100000
+	// solveNodes caps how many partly known registers solve tries, as a
+	// program that ignores A's bits has as many solutions as there are As.
+	solveNodes = 10000000
+)
+
+var errTooManyNodes = errors.New("search too big")
+
+// solveOptions are the limits of solve's search, and what it solves for.
+type solveOptions struct {
+	// Bits is how wide A, and B and C if unknown, can be. 0 means 3 bits
+	// for each output wanted, which is what a program that shifts A down 3
+	// bits per output, as the puzzle's do, needs.
+	Bits int
+	// Limit is how many solutions to return, the smallest; 0 is all.
+	Limit int
+	// UnknownB and UnknownC solve for B and C too, rather than taking them
+	// as given.
+	UnknownB, UnknownC bool
+}
+
+// solution is registers that make the program print what was wanted.
+type solution struct {
+	A, B, C int
+	// AnyB and AnyC say that B or C can start as anything that fits in
+	// Bits, as what's printed doesn't depend on it. B or C is then 0.
+	AnyB, AnyC bool
+}
+
+func (s solution) String() string {
+	str := fmt.Sprintf("A=%d (octal %s)", s.A, strconv.FormatInt(int64(s.A), 8))
+	for _, r := range []struct {
+		name string
+		v    int
+		any  bool
+	}{{"B", s.B, s.AnyB}, {"C", s.C, s.AnyC}} {
+		if r.any {
+			str += " any " + r.name
+		} else {
+			str += fmt.Sprintf(" %s=%d", r.name, r.v)
+		}
+	}
+	return str
+}
+
+// solveResult is what solve found.
+type solveResult struct {
+	// Solutions are ordered by A, then B, then C, each one checked by
+	// running the program on it.
+	Solutions []solution
+	// Total counts all the solutions, of which Solutions may be the first
+	// few.
+	Total int
+	// Nodes counts the partly known registers tried.
+	Nodes int
+}
+
+// as lists the solutions' As.
+func (r solveResult) as() []int {
+	var as []int
+	for _, s := range r.Solutions {
+		as = append(as, s.A)
+	}
+	return as
+}
+
+// solve finds the As, and the Bs and Cs if asked to, that make program
+// print target. B and C otherwise start as given.
+//
+// It treats the registers being solved for as unknown bits, and runs the
+// program on what's known of them: each register is a tbits, and each
+// instruction works out which bits of its result are still known. A's
+// bits are filled in 3 at a time from the bottom. After each chunk, the
+// program is run as far as can be told, and the chunk is dropped if
+// anything it printed can't be what's wanted, if it printed too much, or
+// if it halted having printed too little. A run stops being told when jnz
+// can't tell if A is 0.
+//
+// Once A is known, B's and then C's bits are filled in the same way, but
+// only if the run needed them to tell where it jumps or what it prints.
+// The puzzle's programs set B and C from A before reading them, so any B
+// and C do, and there's nothing more to search.
+//
+// As the program shifts A down, earlier outputs only depend on A's low
+// bits, and so are settled as the low chunks are, which is what keeps
+// the search small.
+func solve(program []operation, b, c int, target []int, o solveOptions) (solveResult, error) {
+	var res solveResult
+	bitsWide := o.Bits
+	if bitsWide == 0 {
+		bitsWide = 3 * len(target)
+	}
+	if bitsWide > 63 {
+		return res, fmt.Errorf("registers can't be over 63 bits wide, got %d", bitsWide)
+	}
+	for _, t := range target {
+		if t < 0 || t > 7 {
+			return res, fmt.Errorf("output %d can't be printed: outputs are 0-7", t)
+		}
+	}
+
+	// fill tries each way the next 3 bits of x from the bottom can be.
+	// Bits past the top have to be 0.
+	fill := func(x tbits, try func(tbits) error) error {
+		bit := bits.TrailingZeros64(^x.known)
+		for d := uint64(0); d < 8; d++ {
+			if d<<bit>>bitsWide != 0 {
+				continue
+			}
+			if err := try(tbits{x.known | 7<<bit, x.val | d<<bit}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var found []solution
+	var search func(a, b, c tbits) error
+	search = func(a, b, c tbits) error {
+		res.Nodes++
+		if res.Nodes > solveNodes {
+			return fmt.Errorf("after %d partial registers: %w", solveNodes, errTooManyNodes)
+		}
+		ok, needs := consistent(program, a, b, c, target)
+		if !ok {
+			return nil
+		}
+		// Once begun on, B or C is filled in all the way, so that an
+		// unknown one can be anything.
+		switch {
+		case !a.isKnown():
+			return fill(a, func(a tbits) error { return search(a, b, c) })
+		case !b.isKnown() && (b.known&1 != 0 || needs&inB != 0):
+			return fill(b, func(b tbits) error { return search(a, b, c) })
+		case !c.isKnown() && (c.known&1 != 0 || needs&inC != 0):
+			return fill(c, func(c tbits) error { return search(a, b, c) })
+		}
+		found = append(found, solution{
+			A: int(a.val), B: int(b.val), C: int(c.val),
+			AnyB: !b.isKnown(), AnyC: !c.isKnown(),
+		})
+		return nil
+	}
+	// Bits past the top are known to be 0 from the start.
+	unknown := tbits{allKnown << bitsWide, 0}
+	sb, sc := exactly(uint64(b)), exactly(uint64(c))
+	if o.UnknownB {
+		sb = unknown
+	}
+	if o.UnknownC {
+		sc = unknown
+	}
+	if err := search(unknown, sb, sc); err != nil {
+		return res, err
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		x, y := found[i], found[j]
+		if x.A != y.A {
+			return x.A < y.A
+		}
+		if x.B != y.B {
+			return x.B < y.B
+		}
+		return x.C < y.C
+	})
+	for _, s := range found {
+		// A run too long for consistent to finish isn't ruled out, but
+		// won't get past execute's limit either.
+		if !prints(program, s.A, s.B, s.C, target) {
+			continue
+		}
+		res.Total++
+		if o.Limit == 0 || len(res.Solutions) < o.Limit {
+			res.Solutions = append(res.Solutions, s)
+		}
+	}
+	return res, nil
+}
+
+// parseOutput reads what a program printed, e.g. "4,6,3".
+func parseOutput(s string) ([]int, error) {
+	var vs []int
+	for _, f := range strings.Split(s, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || v < 0 || v > 7 {
+			return nil, fmt.Errorf("output %q: want 0-7, got %q", s, f)
+		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+// regSet is a set of the registers as they start.
+type regSet uint8
+
+const (
+	inA regSet = 1 << iota
+	inB
+	inC
+)
+
+// consistent runs program with what's known of the registers, and reports
+// whether what it does could still end in it printing target. If it
+// could, needs has the registers whose unknown bits it would have to know
+// to be sure: to tell whether A is 0 at a jnz, or what's printed.
+//
+// tbits are unsigned, while computer's registers are ints: dividing a
+// negative rounds towards 0 rather than shifting, % 8 of a negative is
+// negative, and dividing by 2 to a negative power multiplies. So a value
+// that could be negative isn't worked out through those, and what they
+// give is taken as unknown.
+func consistent(program []operation, a, b, c tbits, target []int) (ok bool, needs regSet) {
+	// fa, fb and fc are which starting registers the unknown bits of a, b
+	// and c come from.
+	var fa, fb, fc regSet
+	if !a.isKnown() {
+		fa = inA
+	}
+	if !b.isKnown() {
+		fb = inB
+	}
+	if !c.isKnown() {
+		fc = inC
+	}
+	printed := 0
+	ip := 0
+	for steps := 0; steps < solveSteps; steps++ {
+		if ip < 0 || ip%2 != 0 {
+			return false, 0
+		}
+		if ip >= 2*len(program) {
+			return printed == len(target), needs
+		}
+		op := program[ip/2]
+		if op.opcode < adv || op.opcode >= invalidOpcode || op.operand < lit0 || op.operand >= invalidOperand {
+			return false, 0
+		}
+		v, fv := exactly(uint64(op.operand)), regSet(0)
+		if op.opcode.operandKind() == comboOperand {
+			switch op.operand {
+			case regA:
+				v, fv = a, fa
+			case regB:
+				v, fv = b, fb
+			case regC:
+				v, fv = c, fc
+			case halt:
+				return false, 0
+			}
+		}
+		// shifted is A / 2^v, and mod8 is v % 8.
+		shifted := func() (tbits, regSet) {
+			if a.negative() || v.negative() {
+				return tbits{}, fa | fv
+			}
+			return a.shrBy(v), fa | fv
+		}
+		mod8 := func() tbits {
+			if v.negative() {
+				return tbits{}
+			}
+			return v.mod8()
+		}
+		next := ip + 2
+		switch op.opcode {
+		case adv:
+			a, fa = shifted()
+		case bxl:
+			b = b.xor(v)
+		case bst:
+			b, fb = mod8(), fv
+		case jnz:
+			isZero, known := a.zero()
+			if !known {
+				// Could go either way; nothing printed so far rules A out.
+				return true, needs | fa
+			}
+			if !isZero {
+				next = int(op.operand)
+			}
+		case bxc:
+			b, fb = b.xor(c), fb|fc
+		case out:
+			m := mod8()
+			if printed == len(target) || !m.could(uint64(target[printed])) {
+				return false, 0
+			}
+			if m.known&7 != 7 {
+				needs |= fv
+			}
+			printed++
+		case bdv:
+			b, fb = shifted()
+		case cdv:
+			c, fc = shifted()
+		}
+		ip = next
+	}
+	// Too long to tell.
+	return true, needs | fa | fb | fc
+}
+
+// prints runs program on a, with execute, and reports whether it prints
+// target. As past what computer is exact for go to bigComputer.
+func prints(program []operation, a, b, c int, target []int) bool {
+	if a < 1<<exactBits {
+		cpu := initComputer(a, b, c, program)
+		cpu.limit = quineSteps
+		return cpu.execute() == nil && equal(cpu.output, target)
+	}
+	cpu := initBigComputer(big.NewInt(int64(a)), big.NewInt(int64(b)), big.NewInt(int64(c)), program)
+	cpu.limit = quineSteps
+	return cpu.execute() == nil && equal(cpu.output, target)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// trace runs a day 17 program and writes what each instruction did, or
+// compares its run against one from a different A:
+//
+//	go run trace.go tracing.go errors.go assembler.go computer.go listing.go util.go [-a N] [-format csv|json] [-diff A] <in file>
+func main() {
+	a := flag.Int("a", -1, "start with this in register A instead of the input's")
+	format := flag.String("format", "csv", "write the trace as csv or json")
+	diff := flag.Int("diff", -1, "instead, compare against a run starting with this in A")
+	context := flag.Int("context", 3, "steps to show before where -diff runs diverge")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: trace [-a N] [-format csv|json] [-diff A [-context N]] <in file>")
+	}
+	lines, err := readLines(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	c, err := parseInput(lines)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if *a >= 0 {
+		c.A = *a
+	}
+	first, err := traced(c.program, c.A, c.B, c.C)
+	if err != nil {
+		log.Fatalf("Error: A=%d: %v", c.A, err)
+	}
+	if *diff < 0 {
+		if err := first.write(os.Stdout, *format); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+	second, err := traced(c.program, *diff, c.B, c.C)
+	if err != nil {
+		log.Fatalf("Error: A=%d: %v", *diff, err)
+	}
+	_, report := diffTraces(first, second, *context)
+	fmt.Printf("1: A=%d\n2: A=%d\n%s", c.A, *diff, report)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type registers struct {
+	A int `json:"a"`
+	B int `json:"b"`
+	C int `json:"c"`
+}
+
+// traceEntry is one instruction run.
+type traceEntry struct {
+	// Step counts from 1.
+	Step    int    `json:"step"`
+	IP      int    `json:"ip"`
+	Op      string `json:"op"`
+	Operand int    `json:"operand"`
+	// Value is the operand as the instruction read it: the literal, or
+	// what the combo operand evaluated to. It's 0 for bxc.
+	Value  int       `json:"value"`
+	Before registers `json:"before"`
+	After  registers `json:"after"`
+	// NextIP is where the instruction left ip, after any jump.
+	NextIP int `json:"next_ip"`
+	// Out is what was printed, if anything; -1 if not.
+	Out int `json:"out"`
+}
+
+type trace []traceEntry
+
+func (c *computer) regs() registers {
+	return registers{c.A, c.B, c.C}
+}
+
+// traceBefore starts an entry for the instruction about to run.
+// v is the operand's value.
+func (c *computer) traceBefore(opc opcode, opa operand, v int) traceEntry {
+	e := traceEntry{
+		Step:    len(c.trace) + 1,
+		IP:      c.ip,
+		Op:      opc.String(),
+		Operand: int(opa),
+		Before:  c.regs(),
+		Out:     -1,
+	}
+	if opc.operandKind() != ignoredOperand {
+		e.Value = v
+	}
+	return e
+}
+
+// traceAfter finishes off e once its instruction has run, and keeps it.
+// outLen is how much output there was before.
+func (c *computer) traceAfter(e traceEntry, outLen int) {
+	if len(c.output) > outLen {
+		e.Out = c.output[outLen]
+	}
+	e.After, e.NextIP = c.regs(), c.ip
+	c.trace = append(c.trace, e)
+}
+
+// undo winds c back to before the last traced instruction, and returns
+// it; ok is false if there's nothing to undo.
+func (c *computer) undo() (e traceEntry, ok bool) {
+	if len(c.trace) == 0 {
+		return e, false
+	}
+	e = c.trace[len(c.trace)-1]
+	c.trace = c.trace[:len(c.trace)-1]
+	c.A, c.B, c.C = e.Before.A, e.Before.B, e.Before.C
+	c.ip = e.IP
+	if e.Out >= 0 {
+		c.output = c.output[:len(c.output)-1]
+	}
+	c.halt = false
+	return e, true
+}
+
+func (e traceEntry) String() string {
+	s := fmt.Sprintf("#%d %4d  %-10s A=%d B=%d C=%d", e.Step, e.IP, operation{opcodeNamed(e.Op), operand(e.Operand)}.text(), e.After.A, e.After.B, e.After.C)
+	if e.Out >= 0 {
+		s += fmt.Sprintf(" out %d", e.Out)
+	}
+	if e.NextIP != e.IP+2 {
+		s += fmt.Sprintf(" -> %d", e.NextIP)
+	}
+	return s
+}
+
+func opcodeNamed(name string) opcode {
+	for o := opcode(adv); o < invalidOpcode; o++ {
+		if o.String() == name {
+			return o
+		}
+	}
+	return invalidOpcode
+}
+
+var csvHeader = []string{"step", "ip", "op", "operand", "value", "a_before", "b_before", "c_before", "a_after", "b_after", "c_after", "next_ip", "out"}
+
+func (t trace) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, e := range t {
+		row := []string{e.Op}
+		for _, v := range []int{e.Operand, e.Value, e.Before.A, e.Before.B, e.Before.C, e.After.A, e.After.B, e.After.C, e.NextIP, e.Out} {
+			row = append(row, strconv.Itoa(v))
+		}
+		cw.Write(append([]string{strconv.Itoa(e.Step), strconv.Itoa(e.IP)}, row...))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (t trace) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if t == nil {
+		t = trace{}
+	}
+	return enc.Encode(t)
+}
+
+// write writes t as CSV or JSON, by name.
+func (t trace) write(w io.Writer, format string) error {
+	switch format {
+	case "csv":
+		return t.writeCSV(w)
+	case "json":
+		return t.writeJSON(w)
+	}
+	return fmt.Errorf("trace format %q: want csv or json", format)
+}
+
+// save writes t to path, as JSON if it ends in .json and CSV otherwise.
+func (t trace) save(path string) error {
+	format := "csv"
+	if filepath.Ext(path) == ".json" {
+		format = "json"
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := t.write(f, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// traceSteps caps how long a traced run goes on, as every step is kept.
+const traceSteps = 100000
+
+// traced runs program from the given registers, recording a trace.
+func traced(program []operation, a, b, c int) (trace, error) {
+	cpu := initComputer(a, b, c, program)
+	cpu.tracing = true
+	cpu.limit = traceSteps
+	err := cpu.execute()
+	return cpu.trace, err
+}
+
+// sameStep is whether a and b ran the same instruction to the same
+// effect: the same output and the same jump. Registers aren't compared, as
+// runs from different starting values always differ there.
+func sameStep(a, b traceEntry) bool {
+	return a.IP == b.IP && a.Op == b.Op && a.Operand == b.Operand && a.NextIP == b.NextIP && a.Out == b.Out
+}
+
+// diffTraces finds the first step at which a and b do something
+// different, and describes it with a few steps of context before; at is
+// -1 if they do the same throughout.
+func diffTraces(a, b trace, context int) (at int, report string) {
+	n := min(len(a), len(b))
+	at = -1
+	for i := 0; i < n; i++ {
+		if !sameStep(a[i], b[i]) {
+			at = i
+			break
+		}
+	}
+	if at < 0 && len(a) == len(b) {
+		return -1, fmt.Sprintf("traces run the same way, %d steps\n", len(a))
+	}
+	var s strings.Builder
+	if at < 0 {
+		at = n
+		s.WriteString(fmt.Sprintf("traces agree for %d steps, then one halts: first %d steps, second %d\n", n, len(a), len(b)))
+	} else {
+		s.WriteString(fmt.Sprintf("traces diverge at step %d\n", at+1))
+	}
+	for i := max(0, at-context); i < at; i++ {
+		s.WriteString("    " + a[i].String() + "\n")
+	}
+	if at < len(a) {
+		s.WriteString("1>  " + a[at].String() + "\n")
+	}
+	if at < len(b) {
+		s.WriteString("2>  " + b[at].String() + "\n")
+	}
+	if at < n {
+		s.WriteString("    differs in: " + strings.Join(differences(a[at], b[at]), ", ") + "\n")
+	}
+	return at, s.String()
+}
+
+func differences(a, b traceEntry) []string {
+	var ds []string
+	check := func(name string, x, y int) {
+		if x != y {
+			ds = append(ds, fmt.Sprintf("%s %d vs %d", name, x, y))
+		}
+	}
+	check("ip", a.IP, b.IP)
+	if a.Op != b.Op {
+		ds = append(ds, fmt.Sprintf("op %s vs %s", a.Op, b.Op))
+	}
+	check("value", a.Value, b.Value)
+	check("A before", a.Before.A, b.Before.A)
+	check("B before", a.Before.B, b.Before.B)
+	check("C before", a.Before.C, b.Before.C)
+	check("A", a.After.A, b.After.A)
+	check("B", a.After.B, b.After.B)
+	check("C", a.After.C, b.After.C)
+	check("next ip", a.NextIP, b.NextIP)
+	check("out", a.Out, b.Out)
+	return ds
+}
@@ -30,3 +30,9 @@ func mustParseInt(s string) int64 {
 	}
 	return v
 }
+
+func assert(s string, b bool) {
+	if !b {
+		log.Fatalf("boom: %v", s)
+	}
+}
@@ -1,22 +1,106 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
-	"os"
+
+	"github.com/phad/advent-of-code-2024/lib/grid"
+	"github.com/phad/advent-of-code-2024/lib/maze"
+	"github.com/phad/advent-of-code-2024/lib/render"
+	"github.com/phad/advent-of-code-2024/lib/search"
 )
 
 /* Example input
- */
+5,4
+4,2
+4,5
+3,0
+2,1
+...
+*/
+
+func runTests() {
+	bytes, err := parseBytes(example)
+	assert(fmt.Sprintf("parseBytes err %v", err), err == nil)
+	m, err := newMemory(7, 7, bytes)
+	assert(fmt.Sprintf("newMemory err %v", err), err == nil)
+	_, res := m.shortest(12)
+	assert(fmt.Sprintf("example after 12: %v want 22 steps", res), res.Found && res.Cost == 22)
+	_, res = m.shortest(0)
+	assert(fmt.Sprintf("example after 0: %v want 12 steps", res), res.Found && res.Cost == 12)
+	_, res = m.shortest(len(bytes))
+	assert(fmt.Sprintf("example after all: %v want no path", res), !res.Found)
+
+	// Generated inputs: the first walls bytes leave a maze to walk, and
+	// the rest must cut it off eventually.
+	for _, o := range []maze.Options{{W: 21, H: 21, Seed: 1}, {W: 31, H: 21, Seed: 2, Braid: 0.3}} {
+		bytes, walls, err := maze.Bytes(o)
+		assert(fmt.Sprintf("%+v: Bytes err %v", o, err), err == nil)
+		m, err := newMemory(o.W, o.H, bytes)
+		assert(fmt.Sprintf("%+v: newMemory err %v", o, err), err == nil)
+		_, res := m.shortest(walls)
+		assert(fmt.Sprintf("%+v: after %d walls %v", o, walls, res), res.Found)
+		n, ok := m.firstBlockingBinary()
+		assert(fmt.Sprintf("%+v: blocked after %d, %t", o, n, ok), ok && n > walls)
+	}
+
+	_, err = newMemory(6, 6, bytes)
+	assert("newMemory should reject bytes outside the grid", err != nil)
+	for _, bad := range [][]string{{"1;2"}, {"a,2"}, {"1,b"}} {
+		_, err := parseBytes(bad)
+		assert(fmt.Sprintf("parseBytes(%q) should fail", bad), err != nil)
+	}
+}
 
 func main() {
 	log.Println("AoC-2024-day18-part1")
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: main <in file>")
+	mf := registerMemFlags(true)
+	ex := render.ExplorerFlags()
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: main [-width N] [-height N] [-n N] [-explore] [-explore-png file] [-explore-gif file] <in file>")
+	}
+	lines, err := readLines(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
 	}
-	lines, err := readLines(os.Args[1])
+
+	runTests()
+
+	bytes, err := parseBytes(lines)
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
+	w, h, n := mf.sizes(len(bytes))
+	m, err := newMemory(w, h, bytes)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	log.Printf("Memory %dx%d, %d of %d bytes fallen", w, h, min(n, len(bytes)), len(bytes))
 
-	log.Printf("Input: %v", lines)
+	g := m.after(n)
+	p := problem(g, m.start(), m.exit())
+	var rec *search.Recording[grid.Point]
+	if ex.Enabled() {
+		rec = &search.Recording[grid.Point]{Every: ex.Every()}
+		p = search.Record(p, rec)
+	}
+	res := search.Solve(search.BFS, p)
+	if !res.Found {
+		log.Fatalf("No way from %v to %v!", m.start(), m.exit())
+	}
+	if rec != nil {
+		rec.AddPath(res.Path)
+		e := render.NewExploration(g, rec, func(p grid.Point) grid.Point { return p })
+		e.Wall = corrupt
+		if err := ex.Write(e); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+	t := render.NewTerminal(nil, 0)
+	t.Highlight[corrupt] = render.Red
+	log.Printf("\n%s", t.Render(g, render.Overlay{Cells: res.Path, Rune: 'O', Colour: render.Green}))
+	log.Printf("Search: %v", res)
+	log.Printf("Steps: %d", res.Cost)
 }
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"testing"
+
+	"github.com/phad/advent-of-code-2024/lib/grid"
+	"github.com/phad/advent-of-code-2024/lib/maze"
+	"github.com/phad/advent-of-code-2024/lib/search"
+)
+
+func runTests() {
+	bytes, err := parseBytes(example)
+	assert(fmt.Sprintf("parseBytes err %v", err), err == nil)
+	m, err := newMemory(7, 7, bytes)
+	assert(fmt.Sprintf("newMemory err %v", err), err == nil)
+	for name, first := range map[string]func() (int, bool){
+		"binary":    m.firstBlockingBinary,
+		"unionfind": m.firstBlockingUnionFind,
+	} {
+		n, ok := first()
+		assert(fmt.Sprintf("%s: blocked %t want true", name, ok), ok)
+		b := m.bytes[n-1]
+		assert(fmt.Sprintf("%s: byte %d at %d,%d want 6,1", name, n, b.X, b.Y), b.X == 6 && b.Y == 1)
+	}
+
+	// Only the first 20 bytes fall, which isn't enough to block the exit.
+	m, err = newMemory(7, 7, bytes[:20])
+	assert(fmt.Sprintf("newMemory err %v", err), err == nil)
+	for name, first := range map[string]func() (int, bool){
+		"binary":    m.firstBlockingBinary,
+		"unionfind": m.firstBlockingUnionFind,
+	} {
+		_, ok := first()
+		assert(fmt.Sprintf("%s: first 20 blocked %t want false", name, ok), !ok)
+	}
+
+	// A byte landing on an already corrupted cell changes nothing, so
+	// mustn't be taken as the one that blocked the exit.
+	m, err = newMemory(3, 3, parseOrDie([]string{"1,0", "1,1", "1,2", "1,1"}))
+	assert(fmt.Sprintf("newMemory err %v", err), err == nil)
+	for name, first := range map[string]func() (int, bool){
+		"binary":    m.firstBlockingBinary,
+		"unionfind": m.firstBlockingUnionFind,
+	} {
+		n, ok := first()
+		assert(fmt.Sprintf("%s: repeated byte: %d, %t want 3, true", name, n, ok), ok && n == 3)
+	}
+
+	// A byte landing on the start or the exit blocks it straight away.
+	for _, at := range []string{"0,0", "2,2"} {
+		m, err = newMemory(3, 3, parseOrDie([]string{at, "1,1"}))
+		assert(fmt.Sprintf("newMemory err %v", err), err == nil)
+		_, res := m.shortest(1)
+		assert(fmt.Sprintf("byte at %s: found a path of %d", at, res.Cost), !res.Found)
+		for name, first := range map[string]func() (int, bool){
+			"binary":    m.firstBlockingBinary,
+			"unionfind": m.firstBlockingUnionFind,
+		} {
+			n, ok := first()
+			assert(fmt.Sprintf("%s: byte at %s: %d, %t want 1, true", name, at, n, ok), ok && n == 1)
+		}
+	}
+}
+
+func parseOrDie(lines []string) []grid.Point {
+	ps, err := parseBytes(lines)
+	assert(fmt.Sprintf("parseBytes err %v", err), err == nil)
+	return ps
+}
+
+var bench = flag.Bool("bench", false, "benchmark the searches on generated 71x71 memory spaces instead of solving")
+
+// randomBytes drops bytes on every cell but the corners, in a random order.
+func randomBytes(w, h int, seed int64) []grid.Point {
+	var ps []grid.Point
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if p := (grid.Point{X: x, Y: y}); p != (grid.Point{}) && p != (grid.Point{X: w - 1, Y: h - 1}) {
+				ps = append(ps, p)
+			}
+		}
+	}
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(ps), func(i, j int) { ps[i], ps[j] = ps[j], ps[i] })
+	return ps
+}
+
+func runBenchmarks() {
+	// 71x71 is the size of the real memory space.
+	mazeBytes, walls, err := maze.Bytes(maze.Options{W: 71, H: 71, Seed: 1, Braid: 0.2})
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	mazed, _ := newMemory(71, 71, mazeBytes)
+	random, _ := newMemory(71, 71, randomBytes(71, 71, 1))
+	last, ok := random.firstBlockingBinary()
+	if !ok {
+		log.Fatalf("Random bytes never block the exit")
+	}
+	for _, c := range []struct {
+		name string
+		m    *memory
+		n    int
+	}{
+		{"maze", mazed, walls},
+		{"1024 random", random, 1024},
+		{"random, just open", random, last - 1},
+		{"random, just blocked", random, last},
+	} {
+		g := c.m.after(c.n)
+		p := problem(g, c.m.start(), c.m.exit())
+		p.Heuristic = func(q grid.Point) int { return c.m.exit().X - q.X + c.m.exit().Y - q.Y }
+		log.Printf("%s, %d bytes:", c.name, c.n)
+		for _, b := range []struct {
+			name  string
+			solve func() search.Result[grid.Point]
+		}{
+			{"BFS", func() search.Result[grid.Point] { return search.Solve(search.BFS, p) }},
+			{"A*", func() search.Result[grid.Point] { return search.Solve(search.AStar, p) }},
+			{"bidirectional BFS", func() search.Result[grid.Point] { return search.SolveBidirectional(search.BFS, p, c.m.exit()) }},
+			{"bidirectional Dijkstra", func() search.Result[grid.Point] {
+				return search.SolveBidirectional(search.Dijkstra, p, c.m.exit())
+			}},
+		} {
+			res := testing.Benchmark(func(tb *testing.B) {
+				for i := 0; i < tb.N; i++ {
+					b.solve()
+				}
+			})
+			log.Printf("  %-24s %-40v %v", b.name, b.solve(), res)
+		}
+	}
+}
+
+func main() {
+	log.Println("AoC-2024-day18-part2")
+	mf := registerMemFlags(false)
+	method := flag.String("method", "binary", "how to find the blocking byte: binary or unionfind")
+	flag.Parse()
+	if *bench {
+		runBenchmarks()
+		return
+	}
+	if flag.NArg() < 1 {
+		log.Fatal("Usage: main [-bench] [-width N] [-height N] [-method binary|unionfind] <in file>")
+	}
+	lines, err := readLines(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	runTests()
+
+	bytes, err := parseBytes(lines)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	w, h, _ := mf.sizes(len(bytes))
+	m, err := newMemory(w, h, bytes)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	var first func() (int, bool)
+	switch *method {
+	case "binary":
+		first = m.firstBlockingBinary
+	case "unionfind":
+		first = m.firstBlockingUnionFind
+	default:
+		log.Fatalf("Unknown -method %q, want binary or unionfind", *method)
+	}
+	n, ok := first()
+	if !ok {
+		log.Fatalf("The exit never gets cut off by any of the %d bytes", len(bytes))
+	}
+	if n == 0 {
+		log.Fatalf("There's no way to the exit even before any bytes fall")
+	}
+	b := m.bytes[n-1]
+	log.Printf("Byte #%d (%s) cuts off the exit", n, *method)
+	log.Printf("First blocking byte: %d,%d", b.X, b.Y)
+}
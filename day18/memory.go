@@ -0,0 +1,201 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/phad/advent-of-code-2024/lib/grid"
+	"github.com/phad/advent-of-code-2024/lib/search"
+	"github.com/phad/advent-of-code-2024/lib/unionfind"
+)
+
+const corrupt = '#'
+
+// memory is the W*H memory space, and the bytes that will fall into it
+// in order.
+type memory struct {
+	w, h  int
+	bytes []grid.Point
+}
+
+func parseBytes(lines []string) ([]grid.Point, error) {
+	var ps []grid.Point
+	for i, l := range lines {
+		if l == "" {
+			continue
+		}
+		xs, ys, ok := strings.Cut(l, ",")
+		if !ok {
+			return nil, fmt.Errorf("line %d: want x,y got %q", i+1, l)
+		}
+		x, err := strconv.Atoi(xs)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		y, err := strconv.Atoi(ys)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		ps = append(ps, grid.Point{X: x, Y: y})
+	}
+	return ps, nil
+}
+
+func newMemory(w, h int, bytes []grid.Point) (*memory, error) {
+	for i, b := range bytes {
+		if b.X < 0 || b.X >= w || b.Y < 0 || b.Y >= h {
+			return nil, fmt.Errorf("byte %d at %v falls outside the %dx%d memory", i, b, w, h)
+		}
+	}
+	return &memory{w: w, h: h, bytes: bytes}, nil
+}
+
+func (m *memory) start() grid.Point { return grid.Point{} }
+func (m *memory) exit() grid.Point  { return grid.Point{X: m.w - 1, Y: m.h - 1} }
+
+// after is the memory once the first n bytes have fallen.
+func (m *memory) after(n int) *grid.Grid[rune] {
+	g := grid.New(m.w, m.h, '.')
+	for _, b := range m.bytes[:min(n, len(m.bytes))] {
+		g.Set(b, corrupt)
+	}
+	return g
+}
+
+// problem is walking from start to exit through g.  There's no standing on
+// a corrupted cell, so a byte landing on the start or the exit leaves no
+// way through, whichever end it's searched from.
+func problem(g *grid.Grid[rune], start, exit grid.Point) search.Problem[grid.Point] {
+	free := func(p grid.Point) bool { return g.Cells[p.Y][p.X] != corrupt }
+	return search.Problem[grid.Point]{
+		Start: start,
+		Neighbours: func(p grid.Point) []search.Edge[grid.Point] {
+			if !free(p) {
+				return nil
+			}
+			var es []search.Edge[grid.Point]
+			for _, n := range g.Neighbours4(p) {
+				if free(n) {
+					es = append(es, search.Edge[grid.Point]{To: n, Cost: 1})
+				}
+			}
+			return es
+		},
+		Goal: func(p grid.Point) bool { return p == exit && free(p) },
+	}
+}
+
+// shortest finds the fewest steps to the exit once n bytes have fallen.
+func (m *memory) shortest(n int) (*grid.Grid[rune], search.Result[grid.Point]) {
+	g := m.after(n)
+	return g, search.Solve(search.BFS, problem(g, m.start(), m.exit()))
+}
+
+// blocked reports whether the exit is cut off once n bytes have fallen.
+// Only whether there's a path matters, not how long it is, so searching
+// from both ends to meet in the middle saves looking at most of the grid.
+func (m *memory) blocked(n int) bool {
+	g := m.after(n)
+	return !search.SolveBidirectional(search.BFS, problem(g, m.start(), m.exit()), m.exit()).Found
+}
+
+// firstBlockingBinary finds how many bytes have to fall to cut off the
+// exit, by binary search over the number fallen.  It returns false if the
+// exit is never cut off.
+func (m *memory) firstBlockingBinary() (int, bool) {
+	n := sort.Search(len(m.bytes)+1, m.blocked)
+	return n, n <= len(m.bytes)
+}
+
+// firstBlockingUnionFind works backwards from every byte having fallen,
+// lifting bytes one at a time and joining the freed cell to its free
+// neighbours, until start and exit are joined up.  The last byte lifted is
+// the one that cut them off.
+func (m *memory) firstBlockingUnionFind() (int, bool) {
+	g := m.after(len(m.bytes))
+	ds := unionfind.New[grid.Point]()
+	free := func(p grid.Point) {
+		ds.Add(p)
+		for _, n := range g.Neighbours4(p) {
+			if g.Cells[n.Y][n.X] != corrupt {
+				ds.Union(p, n)
+			}
+		}
+	}
+	for y := 0; y < m.h; y++ {
+		for x := 0; x < m.w; x++ {
+			if p := (grid.Point{X: x, Y: y}); g.Cells[y][x] != corrupt {
+				free(p)
+			}
+		}
+	}
+	connected := func() bool {
+		return ds.Has(m.start()) && ds.Has(m.exit()) && ds.Same(m.start(), m.exit())
+	}
+	if connected() {
+		return len(m.bytes) + 1, false
+	}
+	for i := len(m.bytes) - 1; i >= 0; i-- {
+		b := m.bytes[i]
+		if !m.fallsOnce(i) {
+			// The same cell gets hit again later on, so it stays corrupt.
+			continue
+		}
+		g.Set(b, '.')
+		free(b)
+		if connected() {
+			return i + 1, true
+		}
+	}
+	// Even with no bytes fallen there's no way through.
+	return 0, true
+}
+
+// fallsOnce reports whether bytes[i] is the first byte to land on its cell.
+func (m *memory) fallsOnce(i int) bool {
+	for _, b := range m.bytes[:i] {
+		if b == m.bytes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type memFlags struct {
+	width, height, n *int
+}
+
+// registerMemFlags registers the memory's size, and if withN, -n for how
+// many bytes fall before walking out, which only part 1 does.
+func registerMemFlags(withN bool) *memFlags {
+	f := &memFlags{
+		width:  flag.Int("width", 0, "memory width (default 7 for the example, 71 for real input)"),
+		height: flag.Int("height", 0, "memory height (default 7 for the example, 71 for real input)"),
+	}
+	if withN {
+		f.n = flag.Int("n", 0, "how many bytes fall before walking out (default 12 for the example, 1024 for real input)")
+	}
+	return f
+}
+
+// sizes fills in defaults by guessing from the input whether it's the
+// example or a real input.
+func (f *memFlags) sizes(numBytes int) (w, h, n int) {
+	w, h, n = 71, 71, 1024
+	if numBytes < 100 {
+		w, h, n = 7, 7, 12
+	}
+	if *f.width > 0 {
+		w = *f.width
+	}
+	if *f.height > 0 {
+		h = *f.height
+	}
+	if f.n != nil && *f.n > 0 {
+		n = *f.n
+	}
+	return w, h, n
+}
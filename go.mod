@@ -0,0 +1,3 @@
+module github.com/phad/advent-of-code-2024
+
+go 1.22
@@ -0,0 +1,127 @@
+package grid
+
+import "fmt"
+
+// Component is one connected region of cells found by Label.
+type Component struct {
+	Label int
+	// Cells in the order the flood fill reached them; Cells[0] is the
+	// top-left-most cell in reading order.
+	Cells []Point
+	// Min and Max are the inclusive corners of the bounding box.
+	Min, Max Point
+	// Perimeter counts cell edges that face something outside the component.
+	Perimeter int
+	// Corners counts the convex and concave corners of the outline. For a
+	// region made of whole cells that's the same as the number of straight
+	// sides, including the sides of any holes.
+	Corners int
+}
+
+func (c *Component) String() string {
+	return fmt.Sprintf("<#%d area:%d perim:%d sides:%d box:%v-%v>", c.Label, c.Area(), c.Perimeter, c.Sides(), c.Min, c.Max)
+}
+
+func (c *Component) Area() int {
+	return len(c.Cells)
+}
+
+func (c *Component) Sides() int {
+	return c.Corners
+}
+
+// Labelling is the result of Label: Labels holds the component label of
+// every cell, which indexes into Components.
+type Labelling struct {
+	Labels     *Grid[int]
+	Components []*Component
+}
+
+// Equal is the usual predicate for Label: neighbours with the same value
+// belong together.
+func Equal[T comparable](a, b T) bool {
+	return a == b
+}
+
+// FloodFill returns every cell reachable from start by stepping between
+// orthogonal neighbours for which same(from, to) holds.
+func FloodFill[T any](g *Grid[T], start Point, same func(a, b T) bool) []Point {
+	if !g.In(start) {
+		return nil
+	}
+	seen := New(g.W, g.H, false)
+	return fill(g, start, same, seen)
+}
+
+func fill[T any](g *Grid[T], start Point, same func(a, b T) bool, seen *Grid[bool]) []Point {
+	seen.Set(start, true)
+	cells := []Point{start}
+	// cells doubles as the BFS queue.
+	for i := 0; i < len(cells); i++ {
+		p := cells[i]
+		for _, n := range g.Neighbours4(p) {
+			if seen.Cells[n.Y][n.X] || !same(g.Cells[p.Y][p.X], g.Cells[n.Y][n.X]) {
+				continue
+			}
+			seen.Cells[n.Y][n.X] = true
+			cells = append(cells, n)
+		}
+	}
+	return cells
+}
+
+// Label splits the grid into connected components, where orthogonal
+// neighbours a and b are connected if same(a, b). Each cell is visited a
+// constant number of times, so this is linear in the size of the grid.
+// Components are numbered from 0 in the reading order of their first cell.
+func Label[T any](g *Grid[T], same func(a, b T) bool) *Labelling {
+	l := &Labelling{Labels: New(g.W, g.H, -1)}
+	seen := New(g.W, g.H, false)
+	for y := 0; y < g.H; y++ {
+		for x := 0; x < g.W; x++ {
+			if seen.Cells[y][x] {
+				continue
+			}
+			c := &Component{Label: len(l.Components)}
+			c.Cells = fill(g, Point{x, y}, same, seen)
+			for _, p := range c.Cells {
+				l.Labels.Cells[p.Y][p.X] = c.Label
+			}
+			l.Components = append(l.Components, c)
+		}
+	}
+	for _, c := range l.Components {
+		l.measure(c)
+	}
+	return l
+}
+
+func (l *Labelling) inside(p Point, label int) bool {
+	v, ok := l.Labels.At(p)
+	return ok && v == label
+}
+
+func (l *Labelling) measure(c *Component) {
+	c.Min, c.Max = c.Cells[0], c.Cells[0]
+	for _, p := range c.Cells {
+		c.Min.X, c.Min.Y = min(c.Min.X, p.X), min(c.Min.Y, p.Y)
+		c.Max.X, c.Max.Y = max(c.Max.X, p.X), max(c.Max.Y, p.Y)
+
+		for i, d := range Dirs4 {
+			if !l.inside(p.Add(d), c.Label) {
+				c.Perimeter++
+			}
+			// Look at the corner between d and the next direction clockwise.
+			e := Dirs4[(i+1)%len(Dirs4)]
+			outD, outE := !l.inside(p.Add(d), c.Label), !l.inside(p.Add(e), c.Label)
+			outDiag := !l.inside(p.Add(d).Add(e), c.Label)
+			if outD && outE {
+				// convex
+				c.Corners++
+			} else if !outD && !outE && outDiag {
+				// concave
+				c.Corners++
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Package grid holds the 2D grid helpers that most days were copy-pasting
+// between their util.go files.
+package grid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Point is a cell position; x grows to the right and y grows downwards.
+type Point struct{ X, Y int }
+
+func (p Point) String() string {
+	return fmt.Sprintf("(%d,%d)", p.X, p.Y)
+}
+
+func (p Point) Add(q Point) Point {
+	return Point{p.X + q.X, p.Y + q.Y}
+}
+
+// Unit steps in each of the four compass directions.
+var (
+	Up    = Point{0, -1}
+	Right = Point{1, 0}
+	Down  = Point{0, 1}
+	Left  = Point{-1, 0}
+
+	// Dirs4 lists the four directions clockwise, starting with Up.
+	Dirs4 = []Point{Up, Right, Down, Left}
+)
+
+// Grid is a rectangular W*H array of cells, indexed Cells[y][x].
+type Grid[T any] struct {
+	W, H  int
+	Cells [][]T
+}
+
+// New returns a w*h grid with every cell set to fill.
+func New[T any](w, h int, fill T) *Grid[T] {
+	g := &Grid[T]{W: w, H: h, Cells: make([][]T, h)}
+	for y := range g.Cells {
+		row := make([]T, w)
+		for x := range row {
+			row[x] = fill
+		}
+		g.Cells[y] = row
+	}
+	return g
+}
+
+// FromLines parses puzzle input into a grid of runes, one row per line.
+func FromLines(in []string, wantSquare bool) (*Grid[rune], error) {
+	g := &Grid[rune]{H: len(in)}
+	for i, r := range in {
+		row := []rune(r)
+		if i == 0 {
+			g.W = len(row)
+			if g.W != g.H && wantSquare {
+				return nil, fmt.Errorf("Grid isn't square: width %d != height %d", g.W, g.H)
+			}
+		}
+		if i > 0 && len(row) != g.W {
+			return nil, fmt.Errorf("Row %d wrong size %d want %d", i, len(row), g.W)
+		}
+		g.Cells = append(g.Cells, row)
+	}
+	return g, nil
+}
+
+func (g *Grid[T]) String() string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("width:%d height:%d\n", g.W, g.H))
+	for _, row := range g.Cells {
+		for _, c := range row {
+			if r, ok := any(c).(rune); ok {
+				s.WriteRune(r)
+			} else {
+				s.WriteString(fmt.Sprint(c))
+			}
+		}
+		s.WriteRune('\n')
+	}
+	return s.String()
+}
+
+// In reports whether p lies inside the grid.
+func (g *Grid[T]) In(p Point) bool {
+	return p.X >= 0 && p.X < g.W && p.Y >= 0 && p.Y < g.H
+}
+
+func (g *Grid[T]) At(p Point) (T, bool) {
+	if !g.In(p) {
+		var nope T
+		return nope, false
+	}
+	return g.Cells[p.Y][p.X], true
+}
+
+func (g *Grid[T]) Set(p Point, v T) bool {
+	if !g.In(p) {
+		return false
+	}
+	g.Cells[p.Y][p.X] = v
+	return true
+}
+
+// Neighbours4 returns the in-grid orthogonal neighbours of p.
+func (g *Grid[T]) Neighbours4(p Point) []Point {
+	var ns []Point
+	for _, d := range Dirs4 {
+		if n := p.Add(d); g.In(n) {
+			ns = append(ns, n)
+		}
+	}
+	return ns
+}
+
+// Clone returns a deep copy of g.
+func (g *Grid[T]) Clone() *Grid[T] {
+	c := &Grid[T]{W: g.W, H: g.H, Cells: make([][]T, len(g.Cells))}
+	for y, row := range g.Cells {
+		c.Cells[y] = append([]T(nil), row...)
+	}
+	return c
+}
@@ -0,0 +1,144 @@
+package grid
+
+import "fmt"
+
+// View is a read-only window onto a Grid, possibly rotated, flipped or
+// transposed. It shares the grid's cells rather than copying them, so
+// transforms are cheap to stack and see any later changes to the grid.
+type View[T any] struct {
+	W, H int
+	g    *Grid[T]
+	// The affine map from view to grid coordinates:
+	//   gx = ox + xx*x + xy*y
+	//   gy = oy + yx*x + yy*y
+	ox, xx, xy int
+	oy, yx, yy int
+}
+
+// View returns an untransformed view of the whole grid.
+func (g *Grid[T]) View() *View[T] {
+	return &View[T]{W: g.W, H: g.H, g: g, xx: 1, yy: 1}
+}
+
+// Source maps a point in the view to the grid cell it shows.
+func (v *View[T]) Source(p Point) Point {
+	return Point{
+		X: v.ox + v.xx*p.X + v.xy*p.Y,
+		Y: v.oy + v.yx*p.X + v.yy*p.Y,
+	}
+}
+
+func (v *View[T]) In(p Point) bool {
+	return p.X >= 0 && p.X < v.W && p.Y >= 0 && p.Y < v.H
+}
+
+func (v *View[T]) At(p Point) (T, bool) {
+	if !v.In(p) {
+		var nope T
+		return nope, false
+	}
+	s := v.Source(p)
+	return v.g.Cells[s.Y][s.X], true
+}
+
+// then returns the view you get by looking at v through a transform of
+// size w*h, where view point (x', y') shows v's point
+// (cx + fxx*x' + fxy*y', cy + fyx*x' + fyy*y').
+func (v *View[T]) then(w, h, cx, fxx, fxy, cy, fyx, fyy int) *View[T] {
+	return &View[T]{
+		W: w, H: h, g: v.g,
+		ox: v.ox + v.xx*cx + v.xy*cy,
+		xx: v.xx*fxx + v.xy*fyx,
+		xy: v.xx*fxy + v.xy*fyy,
+		oy: v.oy + v.yx*cx + v.yy*cy,
+		yx: v.yx*fxx + v.yy*fyx,
+		yy: v.yx*fxy + v.yy*fyy,
+	}
+}
+
+// Rotate90 turns the view a quarter turn clockwise.
+func (v *View[T]) Rotate90() *View[T] {
+	return v.then(v.H, v.W, 0, 0, 1, v.H-1, -1, 0)
+}
+
+func (v *View[T]) Rotate180() *View[T] {
+	return v.then(v.W, v.H, v.W-1, -1, 0, v.H-1, 0, -1)
+}
+
+// Rotate270 turns the view a quarter turn anticlockwise.
+func (v *View[T]) Rotate270() *View[T] {
+	return v.then(v.H, v.W, v.W-1, 0, -1, 0, 1, 0)
+}
+
+// FlipH mirrors the view left to right.
+func (v *View[T]) FlipH() *View[T] {
+	return v.then(v.W, v.H, v.W-1, -1, 0, 0, 0, 1)
+}
+
+// FlipV mirrors the view top to bottom.
+func (v *View[T]) FlipV() *View[T] {
+	return v.then(v.W, v.H, 0, 1, 0, v.H-1, 0, -1)
+}
+
+// Transpose mirrors the view in its main (top-left to bottom-right)
+// diagonal.
+func (v *View[T]) Transpose() *View[T] {
+	return v.then(v.H, v.W, 0, 0, 1, 0, 1, 0)
+}
+
+// Window is the w*h part of the view whose top-left corner is at min.
+func (v *View[T]) Window(min Point, w, h int) (*View[T], error) {
+	if w < 0 || h < 0 || min.X < 0 || min.Y < 0 || min.X+w > v.W || min.Y+h > v.H {
+		return nil, fmt.Errorf("window %dx%d at %v doesn't fit in %dx%d view", w, h, min, v.W, v.H)
+	}
+	return v.then(w, h, min.X, 1, 0, min.Y, 0, 1), nil
+}
+
+// Rows lists the points of each row of the view, left to right.
+func (v *View[T]) Rows() [][]Point {
+	rows := make([][]Point, v.H)
+	for y := range rows {
+		row := make([]Point, v.W)
+		for x := range row {
+			row[x] = Point{x, y}
+		}
+		rows[y] = row
+	}
+	return rows
+}
+
+// Diagonals lists the points of each diagonal running down and to the
+// right, starting from the bottom-left corner and ending at the top-right
+// one. Diagonals running the other three ways are the Diagonals of the
+// rotated views.
+func (v *View[T]) Diagonals() [][]Point {
+	var diags [][]Point
+	for d := -(v.H - 1); d < v.W; d++ {
+		// Every point on a diagonal has the same x-y == d.
+		var line []Point
+		for y := max(0, -d); y < v.H && y+d < v.W; y++ {
+			line = append(line, Point{y + d, y})
+		}
+		diags = append(diags, line)
+	}
+	return diags
+}
+
+// Values reads the cells at the given view points.
+func (v *View[T]) Values(ps []Point) []T {
+	vals := make([]T, len(ps))
+	for i, p := range ps {
+		s := v.Source(p)
+		vals[i] = v.g.Cells[s.Y][s.X]
+	}
+	return vals
+}
+
+// Grid copies the view out into a grid of its own.
+func (v *View[T]) Grid() *Grid[T] {
+	g := &Grid[T]{W: v.W, H: v.H}
+	for _, row := range v.Rows() {
+		g.Cells = append(g.Cells, v.Values(row))
+	}
+	return g
+}
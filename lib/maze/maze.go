@@ -0,0 +1,210 @@
+// Package maze generates random mazes, for trying the grid searches out
+// on something bigger than the puzzle examples. The same Options always
+// give the same maze.
+package maze
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/phad/advent-of-code-2024/lib/grid"
+)
+
+const (
+	Wall = '#'
+	Open = '.'
+)
+
+type Options struct {
+	// W and H are the size of the grid, walls included. Both must be odd,
+	// so that the maze's passages and walls fit exactly.
+	W, H int
+	Seed int64
+	// Braid is the fraction of dead ends to knock through to a neighbour,
+	// which adds loops: 0 leaves a perfect maze, with exactly one way
+	// between any two cells, and 1 leaves no dead ends at all.
+	Braid float64
+	// Border puts a wall all the way round, as the reindeer maze has.
+	// Without one, passages run along the edges of the grid, as in the
+	// memory space.
+	Border bool
+}
+
+func (o Options) validate() error {
+	min := 3
+	if o.Border {
+		min = 5
+	}
+	if o.W < min || o.H < min || o.W%2 == 0 || o.H%2 == 0 {
+		return fmt.Errorf("maze size %dx%d must be odd and at least %dx%d", o.W, o.H, min, min)
+	}
+	if o.Braid < 0 || o.Braid > 1 {
+		return fmt.Errorf("braid %v must be between 0 and 1", o.Braid)
+	}
+	return nil
+}
+
+// lattice maps between maze cells and grid points. Cells sit on every other
+// grid point, with the walls between them on the points in between.
+type lattice struct {
+	cw, ch int
+	off    int
+}
+
+func (l lattice) point(cx, cy int) grid.Point {
+	return grid.Point{X: l.off + 2*cx, Y: l.off + 2*cy}
+}
+
+// Generate carves a maze with a randomised depth-first search (the
+// recursive backtracker), then braids it. Cells are Open and everything
+// else is Wall.
+func Generate(o Options) (*grid.Grid[rune], error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	l := lattice{off: 0}
+	if o.Border {
+		l.off = 1
+	}
+	l.cw, l.ch = (o.W-2*l.off+1)/2, (o.H-2*l.off+1)/2
+
+	r := rand.New(rand.NewSource(o.Seed))
+	g := grid.New(o.W, o.H, rune(Wall))
+	type cell struct{ x, y int }
+	visited := make([][]bool, l.ch)
+	for y := range visited {
+		visited[y] = make([]bool, l.cw)
+	}
+	steps := []cell{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+
+	// The search keeps its own stack, as a recursive one would go very deep
+	// on big mazes.
+	stack := []cell{{0, 0}}
+	visited[0][0] = true
+	g.Set(l.point(0, 0), Open)
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		moved := false
+		for _, i := range r.Perm(len(steps)) {
+			n := cell{c.x + steps[i].x, c.y + steps[i].y}
+			if n.x < 0 || n.x >= l.cw || n.y < 0 || n.y >= l.ch || visited[n.y][n.x] {
+				continue
+			}
+			visited[n.y][n.x] = true
+			from, to := l.point(c.x, c.y), l.point(n.x, n.y)
+			g.Set(grid.Point{X: (from.X + to.X) / 2, Y: (from.Y + to.Y) / 2}, Open)
+			g.Set(to, Open)
+			stack = append(stack, n)
+			moved = true
+			break
+		}
+		if !moved {
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	braid(g, l, r, o.Braid)
+	return g, nil
+}
+
+// braid knocks a wall out of some of the dead ends, preferring one that
+// joins up with another dead end, so as to remove two at once.
+func braid(g *grid.Grid[rune], l lattice, r *rand.Rand, frac float64) {
+	if frac == 0 {
+		return
+	}
+	walls := func(p grid.Point) (ws []grid.Point) {
+		for _, d := range grid.Dirs4 {
+			w := p.Add(d)
+			// Only walls with a cell on the far side can be knocked out,
+			// not the border.
+			if v, ok := g.At(w); ok && v == Wall {
+				if _, ok := g.At(w.Add(d)); ok {
+					ws = append(ws, w)
+				}
+			}
+		}
+		return ws
+	}
+	deadEnd := func(p grid.Point) bool {
+		open := 0
+		for _, n := range g.Neighbours4(p) {
+			if v, _ := g.At(n); v == Open {
+				open++
+			}
+		}
+		return open == 1
+	}
+	for cy := 0; cy < l.ch; cy++ {
+		for cx := 0; cx < l.cw; cx++ {
+			p := l.point(cx, cy)
+			// Draw for every dead end, knocked through or not, so the
+			// result only depends on the seed.
+			if !deadEnd(p) || r.Float64() >= frac {
+				continue
+			}
+			ws := walls(p)
+			if len(ws) == 0 {
+				continue
+			}
+			pick := ws[r.Intn(len(ws))]
+			for _, w := range ws {
+				if deadEnd(grid.Point{X: 2*w.X - p.X, Y: 2*w.Y - p.Y}) {
+					pick = w
+					break
+				}
+			}
+			g.Set(pick, Open)
+		}
+	}
+}
+
+// Reindeer generates a maze in the reindeer maze's format: square, walled
+// all the way round, starting at S in the bottom-left corner and ending at
+// E in the top-right one.
+func Reindeer(o Options) (*grid.Grid[rune], error) {
+	if o.W != o.H {
+		return nil, fmt.Errorf("reindeer mazes must be square, not %dx%d", o.W, o.H)
+	}
+	o.Border = true
+	g, err := Generate(o)
+	if err != nil {
+		return nil, err
+	}
+	g.Set(grid.Point{X: 1, Y: o.H - 2}, 'S')
+	g.Set(grid.Point{X: o.W - 2, Y: 1}, 'E')
+	return g, nil
+}
+
+// Bytes generates an input for the memory space: a list of bytes to drop
+// onto a W*H grid, walking from the top-left corner to the bottom-right
+// one. The first walls bytes build a maze, and the rest fill in the other
+// cells in a random order until every one but the corners is corrupted,
+// so there's always a first byte that cuts the exit off.
+func Bytes(o Options) (bytes []grid.Point, walls int, err error) {
+	o.Border = false
+	g, err := Generate(o)
+	if err != nil {
+		return nil, 0, err
+	}
+	// A separate source from the maze's, so that the order doesn't
+	// depend on how many numbers carving the maze used up.
+	r := rand.New(rand.NewSource(o.Seed + 1))
+	start, exit := grid.Point{}, grid.Point{X: o.W - 1, Y: o.H - 1}
+	var open []grid.Point
+	for y, row := range g.Cells {
+		for x, c := range row {
+			p := grid.Point{X: x, Y: y}
+			switch {
+			case c == Wall:
+				bytes = append(bytes, p)
+			case p != start && p != exit:
+				open = append(open, p)
+			}
+		}
+	}
+	walls = len(bytes)
+	r.Shuffle(len(bytes), func(i, j int) { bytes[i], bytes[j] = bytes[j], bytes[i] })
+	r.Shuffle(len(open), func(i, j int) { open[i], open[j] = open[j], open[i] })
+	return append(bytes, open...), walls, nil
+}
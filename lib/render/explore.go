@@ -0,0 +1,281 @@
+package render
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"log"
+	"strings"
+
+	"github.com/phad/advent-of-code-2024/lib/grid"
+	"github.com/phad/advent-of-code-2024/lib/search"
+)
+
+// Exploration is a search.Recording laid over the grid that was searched.
+// Each cell is coloured by the cost the search had reached when it first
+// expanded it, from blue near the start to red far away, with the final
+// paths drawn on top. That makes it easy to see where a search spent its
+// time.
+type Exploration struct {
+	Base *grid.Grid[rune]
+	// Wall, if not 0, is the rune of cells that can't be entered. They're
+	// drawn in grey.
+	Wall rune
+
+	order     []grid.Point
+	costs     []int
+	paths     [][]grid.Point
+	snapshots []search.Snapshot[grid.Point]
+	maxCost   int
+}
+
+// NewExploration flattens rec onto base, using pos to find where on the
+// grid each search state is. Several states can share a cell, e.g. a
+// position faced in different directions; a cell takes the cost of the
+// first of them to be expanded.
+func NewExploration[S comparable](base *grid.Grid[rune], rec *search.Recording[S], pos func(S) grid.Point) *Exploration {
+	e := &Exploration{Base: base}
+	points := func(ss []S) []grid.Point {
+		ps := make([]grid.Point, len(ss))
+		for i, s := range ss {
+			ps[i] = pos(s)
+		}
+		return ps
+	}
+	e.order = points(rec.Order)
+	e.costs = rec.Costs
+	for _, c := range e.costs {
+		e.maxCost = max(e.maxCost, c)
+	}
+	for _, p := range rec.Paths {
+		e.paths = append(e.paths, points(p))
+	}
+	for _, s := range rec.Snapshots {
+		e.snapshots = append(e.snapshots, search.Snapshot[grid.Point]{Step: s.Step, Frontier: points(s.Frontier)})
+	}
+	return e
+}
+
+// Steps is how many expansions were recorded.
+func (e *Exploration) Steps() int {
+	return len(e.order)
+}
+
+const (
+	cellUnseen = iota
+	cellWall
+	cellPath
+	cellFrontier
+	cellBand
+)
+
+// bands is how many shades of distance images use.
+const bands = 32
+
+// band places cost on a scale from 0 to n-1.
+func (e *Exploration) band(cost, n int) int {
+	if e.maxCost == 0 {
+		return 0
+	}
+	return cost * (n - 1) / e.maxCost
+}
+
+// firstCosts maps each cell expanded in the first steps expansions to the
+// cost it was first expanded at.
+func (e *Exploration) firstCosts(steps int) map[grid.Point]int {
+	first := map[grid.Point]int{}
+	for i, p := range e.order[:steps] {
+		if _, ok := first[p]; !ok {
+			first[p] = e.costs[i]
+		}
+	}
+	return first
+}
+
+// cells draws the state after steps expansions, as palette indices.
+// Paths are only drawn once the search is over.
+func (e *Exploration) cells(steps int, frontier []grid.Point) *grid.Grid[int] {
+	g := grid.New(e.Base.W, e.Base.H, cellUnseen)
+	for y, row := range e.Base.Cells {
+		for x, r := range row {
+			if e.Wall != 0 && r == e.Wall {
+				g.Cells[y][x] = cellWall
+			}
+		}
+	}
+	for p, c := range e.firstCosts(steps) {
+		g.Set(p, cellBand+e.band(c, bands))
+	}
+	for _, p := range frontier {
+		g.Set(p, cellFrontier)
+	}
+	if steps == len(e.order) {
+		for _, path := range e.paths {
+			for _, p := range path {
+				g.Set(p, cellPath)
+			}
+		}
+	}
+	return g
+}
+
+func palette() Palette[int] {
+	p := Palette[int]{
+		Default: color.RGBA{0x20, 0x20, 0x20, 0xff},
+		Colours: map[int]color.Color{
+			cellWall:     color.RGBA{0x70, 0x70, 0x70, 0xff},
+			cellPath:     color.White,
+			cellFrontier: color.RGBA{0xff, 0x00, 0xff, 0xff},
+		},
+	}
+	for b := 0; b < bands; b++ {
+		p.Colours[cellBand+b] = gradient(float64(b) / (bands - 1))
+	}
+	return p
+}
+
+// gradient runs from blue at 0 through cyan, green and yellow to red at 1.
+func gradient(f float64) color.Color {
+	stops := []color.RGBA{
+		{0x20, 0x40, 0xff, 0xff},
+		{0x00, 0xd0, 0xff, 0xff},
+		{0x20, 0xd0, 0x20, 0xff},
+		{0xff, 0xe0, 0x00, 0xff},
+		{0xff, 0x20, 0x20, 0xff},
+	}
+	pos := f * float64(len(stops)-1)
+	i := min(int(pos), len(stops)-2)
+	t := pos - float64(i)
+	mix := func(a, b uint8) uint8 { return uint8(float64(a) + t*(float64(b)-float64(a))) }
+	a, b := stops[i], stops[i+1]
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 0xff}
+}
+
+// termBands are the terminal colours standing in for the image gradient.
+var termBands = []Colour{Blue, Cyan, Green, Yellow, Red}
+
+// ASCII draws the finished exploration as coloured text. Path cells are
+// drawn as O, keeping their distance colour.
+func (e *Exploration) ASCII() string {
+	t := NewTerminal(nil, 0)
+	if e.Wall != 0 {
+		t.Highlight[e.Wall] = Grey
+	}
+	// Paths can end on a goal that was never expanded, so has no colour.
+	overlays := []Overlay{{Rune: 'O', Colour: White}}
+	onPath := map[grid.Point]bool{}
+	for _, path := range e.paths {
+		for _, p := range path {
+			onPath[p] = true
+		}
+		overlays[0].Cells = append(overlays[0].Cells, path...)
+	}
+	for _, c := range termBands {
+		overlays = append(overlays, Overlay{Colour: c}, Overlay{Rune: 'O', Colour: c})
+	}
+	for p, c := range e.firstCosts(len(e.order)) {
+		o := &overlays[1+2*e.band(c, len(termBands))]
+		if onPath[p] {
+			o = &overlays[2+2*e.band(c, len(termBands))]
+		}
+		o.Cells = append(o.Cells, p)
+	}
+	var s strings.Builder
+	s.WriteString(t.Render(e.Base, overlays...))
+	s.WriteString("cost 0 ")
+	for i, c := range termBands {
+		s.WriteString(c.wrap(fmt.Sprintf("%d", e.maxCost*i/max(len(termBands)-1, 1))))
+		s.WriteRune(' ')
+	}
+	s.WriteString(fmt.Sprintf("(%d expanded)\n", len(e.order)))
+	return s.String()
+}
+
+func (e *Exploration) SavePNG(path string, scale int) error {
+	r, err := NewRenderer(palette(), scale)
+	if err != nil {
+		return err
+	}
+	return r.SavePNG(path, e.cells(len(e.order), nil))
+}
+
+// SaveGIF animates the search in at most frames frames, ending on the
+// finished picture. Frontiers are shown if the recording took snapshots.
+func (e *Exploration) SaveGIF(path string, scale, delay, frames int) error {
+	r, err := NewRenderer(palette(), scale)
+	if err != nil {
+		return err
+	}
+	anim := r.NewAnimation(delay)
+	frames = max(frames, 2)
+	if len(e.snapshots) > 0 {
+		every := (len(e.snapshots) + frames - 2) / (frames - 1)
+		for i := 0; i < len(e.snapshots); i += every {
+			s := e.snapshots[i]
+			anim.AddFrame(e.cells(s.Step, s.Frontier))
+		}
+	} else {
+		for f := 1; f < frames; f++ {
+			anim.AddFrame(e.cells(len(e.order)*f/frames, nil))
+		}
+	}
+	anim.AddFrame(e.cells(len(e.order), nil))
+	return anim.SaveGIF(path)
+}
+
+// Explorer draws a search as the command line asked it to.
+type Explorer struct {
+	ascii                *bool
+	pngPath, gifPath     *string
+	scale, delay, frames *int
+	every                *int
+}
+
+// ExplorerFlags registers -explore, -explore-png, -explore-gif,
+// -explore-scale, -explore-delay, -explore-frames and -explore-every. Call
+// it before flag.Parse.
+func ExplorerFlags() *Explorer {
+	return &Explorer{
+		ascii:   flag.Bool("explore", false, "print the search's exploration, coloured by distance"),
+		pngPath: flag.String("explore-png", "", "draw the search's exploration to this PNG file"),
+		gifPath: flag.String("explore-gif", "", "animate the search's exploration to this GIF file"),
+		scale:   flag.Int("explore-scale", 4, "pixels per grid cell in exploration images"),
+		delay:   flag.Int("explore-delay", 5, "exploration GIF frame delay in 100ths of a second"),
+		frames:  flag.Int("explore-frames", 100, "most frames to put in the exploration GIF"),
+		every:   flag.Int("explore-every", 20, "snapshot the frontier every N expansions for the GIF"),
+	}
+}
+
+// Enabled reports whether any output was asked for, so callers can skip
+// recording otherwise.
+func (ex *Explorer) Enabled() bool {
+	return *ex.ascii || *ex.pngPath != "" || *ex.gifPath != ""
+}
+
+// Every is what to set search.Recording.Every to.
+func (ex *Explorer) Every() int {
+	if *ex.gifPath == "" {
+		return 0
+	}
+	return *ex.every
+}
+
+// Write produces whichever outputs were asked for.
+func (ex *Explorer) Write(e *Exploration) error {
+	if *ex.ascii {
+		log.Printf("Exploration:\n%s", e.ASCII())
+	}
+	if *ex.pngPath != "" {
+		if err := e.SavePNG(*ex.pngPath, *ex.scale); err != nil {
+			return err
+		}
+		log.Printf("Wrote exploration to %s", *ex.pngPath)
+	}
+	if *ex.gifPath != "" {
+		if err := e.SaveGIF(*ex.gifPath, *ex.scale, *ex.delay, *ex.frames); err != nil {
+			return err
+		}
+		log.Printf("Wrote exploration of %d steps to %s", e.Steps(), *ex.gifPath)
+	}
+	return nil
+}
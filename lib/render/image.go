@@ -0,0 +1,155 @@
+// Package render draws grids for visual debugging: as images (PNG
+// snapshots and animated GIFs) and on the terminal.
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/phad/advent-of-code-2024/lib/grid"
+)
+
+// Palette maps cell values to colours. Values without an entry in Colours
+// are drawn in Default.
+type Palette[T comparable] struct {
+	Colours map[T]color.Color
+	Default color.Color
+}
+
+// Renderer draws each cell of a grid as a Scale*Scale block of pixels.
+type Renderer[T comparable] struct {
+	palette Palette[T]
+	scale   int
+	// colours is the palette as the fixed, ordered list that image.Paletted
+	// and GIF frames need.
+	colours color.Palette
+	index   map[T]uint8
+}
+
+func NewRenderer[T comparable](p Palette[T], scale int) (*Renderer[T], error) {
+	if scale < 1 {
+		return nil, fmt.Errorf("scale %d must be at least 1", scale)
+	}
+	if p.Default == nil {
+		p.Default = color.Black
+	}
+	r := &Renderer[T]{palette: p, scale: scale, index: map[T]uint8{}}
+
+	// Order the colours so that output is the same on every run, whatever
+	// order the map iterates in.
+	seen := map[color.RGBA]bool{}
+	var distinct []color.RGBA
+	add := func(c color.Color) {
+		rgba := color.RGBAModel.Convert(c).(color.RGBA)
+		if !seen[rgba] {
+			seen[rgba] = true
+			distinct = append(distinct, rgba)
+		}
+	}
+	add(p.Default)
+	for _, c := range p.Colours {
+		add(c)
+	}
+	if len(distinct) > 256 {
+		return nil, fmt.Errorf("palette has %d colours, at most 256 fit in an image", len(distinct))
+	}
+	sort.Slice(distinct[1:], func(i, j int) bool {
+		a, b := distinct[i+1], distinct[j+1]
+		if a.R != b.R {
+			return a.R < b.R
+		}
+		if a.G != b.G {
+			return a.G < b.G
+		}
+		if a.B != b.B {
+			return a.B < b.B
+		}
+		return a.A < b.A
+	})
+	for _, c := range distinct {
+		r.colours = append(r.colours, c)
+	}
+	for v, c := range p.Colours {
+		r.index[v] = uint8(r.colours.Index(c))
+	}
+	return r, nil
+}
+
+// Image draws g. The default colour is always palette index 0.
+func (r *Renderer[T]) Image(g *grid.Grid[T]) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, g.W*r.scale, g.H*r.scale), r.colours)
+	for y, row := range g.Cells {
+		for x, v := range row {
+			idx := r.index[v]
+			if idx == 0 {
+				continue
+			}
+			for py := y * r.scale; py < (y+1)*r.scale; py++ {
+				for px := x * r.scale; px < (x+1)*r.scale; px++ {
+					img.SetColorIndex(px, py, idx)
+				}
+			}
+		}
+	}
+	return img
+}
+
+func (r *Renderer[T]) WritePNG(w io.Writer, g *grid.Grid[T]) error {
+	return png.Encode(w, r.Image(g))
+}
+
+func (r *Renderer[T]) SavePNG(path string, g *grid.Grid[T]) error {
+	return saveTo(path, func(w io.Writer) error { return r.WritePNG(w, g) })
+}
+
+// Animation collects frames of a simulation for an animated GIF.
+type Animation[T comparable] struct {
+	r *Renderer[T]
+	// Delay between frames, in 100ths of a second.
+	delay int
+	gif   gif.GIF
+}
+
+func (r *Renderer[T]) NewAnimation(delay int) *Animation[T] {
+	return &Animation[T]{r: r, delay: delay}
+}
+
+// AddFrame renders g straight away, so the caller is free to keep
+// mutating it afterwards.
+func (a *Animation[T]) AddFrame(g *grid.Grid[T]) {
+	a.gif.Image = append(a.gif.Image, a.r.Image(g))
+	a.gif.Delay = append(a.gif.Delay, a.delay)
+}
+
+func (a *Animation[T]) Len() int {
+	return len(a.gif.Image)
+}
+
+func (a *Animation[T]) WriteGIF(w io.Writer) error {
+	if a.Len() == 0 {
+		return fmt.Errorf("animation has no frames")
+	}
+	return gif.EncodeAll(w, &a.gif)
+}
+
+func (a *Animation[T]) SaveGIF(path string) error {
+	return saveTo(path, a.WriteGIF)
+}
+
+func saveTo(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,91 @@
+package render
+
+import (
+	"flag"
+	"log"
+
+	"github.com/phad/advent-of-code-2024/lib/grid"
+)
+
+// Recorder is what a day wires its frame hook to. It keeps the last frame
+// for a PNG snapshot and samples frames into an animated GIF, depending on
+// which outputs were asked for.
+type Recorder[T comparable] struct {
+	palette Palette[T]
+
+	pngPath, gifPath *string
+	every, scale     *int
+	delay            *int
+
+	r     *Renderer[T]
+	anim  *Animation[T]
+	last  *grid.Grid[T]
+	count int
+}
+
+// RecorderFlags registers -png, -gif, -every, -scale and -delay on the
+// command line. Call it before flag.Parse.
+func RecorderFlags[T comparable](p Palette[T]) *Recorder[T] {
+	return &Recorder[T]{
+		palette: p,
+		pngPath: flag.String("png", "", "write the final frame to this PNG file"),
+		gifPath: flag.String("gif", "", "write an animation of the run to this GIF file"),
+		every:   flag.Int("every", 1, "only put every Nth frame in the GIF"),
+		scale:   flag.Int("scale", 4, "pixels per grid cell in images"),
+		delay:   flag.Int("delay", 5, "GIF frame delay in 100ths of a second"),
+	}
+}
+
+// Enabled reports whether any image output was requested, so callers can
+// skip building frames at all when it wasn't.
+func (rec *Recorder[T]) Enabled() bool {
+	return *rec.pngPath != "" || *rec.gifPath != ""
+}
+
+func (rec *Recorder[T]) renderer() *Renderer[T] {
+	if rec.r == nil {
+		r, err := NewRenderer(rec.palette, *rec.scale)
+		if err != nil {
+			log.Fatalf("Renderer: %v", err)
+		}
+		rec.r = r
+	}
+	return rec.r
+}
+
+// Frame is the hook to hand to a simulation. g may be mutated once Frame
+// returns.
+func (rec *Recorder[T]) Frame(g *grid.Grid[T]) {
+	if !rec.Enabled() {
+		return
+	}
+	if *rec.pngPath != "" {
+		rec.last = g.Clone()
+	}
+	if *rec.gifPath != "" {
+		if rec.anim == nil {
+			rec.anim = rec.renderer().NewAnimation(*rec.delay)
+		}
+		if rec.count%max(*rec.every, 1) == 0 {
+			rec.anim.AddFrame(g)
+		}
+	}
+	rec.count++
+}
+
+// Close writes out whichever files were requested.
+func (rec *Recorder[T]) Close() error {
+	if *rec.pngPath != "" && rec.last != nil {
+		if err := rec.renderer().SavePNG(*rec.pngPath, rec.last); err != nil {
+			return err
+		}
+		log.Printf("Wrote final frame to %s", *rec.pngPath)
+	}
+	if *rec.gifPath != "" && rec.anim != nil {
+		if err := rec.anim.SaveGIF(*rec.gifPath); err != nil {
+			return err
+		}
+		log.Printf("Wrote %d of %d frames to %s", rec.anim.Len(), rec.count, *rec.gifPath)
+	}
+	return nil
+}
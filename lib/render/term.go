@@ -0,0 +1,149 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/phad/advent-of-code-2024/lib/grid"
+)
+
+// Colour is an ANSI SGR foreground colour code.
+type Colour int
+
+const (
+	NoColour Colour = 0
+	Red      Colour = 31
+	Green    Colour = 32
+	Yellow   Colour = 33
+	Blue     Colour = 34
+	Magenta  Colour = 35
+	Cyan     Colour = 36
+	White    Colour = 37
+	Grey     Colour = 90
+)
+
+const (
+	csi        = "\x1b["
+	reset      = csi + "0m"
+	hideCursor = csi + "?25l"
+	showCursor = csi + "?25h"
+)
+
+func (c Colour) wrap(s string) string {
+	if c == NoColour {
+		return s
+	}
+	return fmt.Sprintf("%s%dm%s%s", csi, int(c), s, reset)
+}
+
+// Overlay draws over the top of the grid without changing it: a path, or
+// markers for particular cells.
+type Overlay struct {
+	Cells []grid.Point
+	// Rune replaces the cell's own rune, unless it's 0.
+	Rune   rune
+	Colour Colour
+}
+
+func Path(cells []grid.Point, c Colour) Overlay {
+	return Overlay{Cells: cells, Colour: c}
+}
+
+func Marker(p grid.Point, r rune, c Colour) Overlay {
+	return Overlay{Cells: []grid.Point{p}, Rune: r, Colour: c}
+}
+
+// Terminal draws rune grids with ANSI colours. Drawing several frames in a
+// row redraws them in place, which animates the grid.
+type Terminal struct {
+	// Highlight colours cells holding particular runes.
+	Highlight map[rune]Colour
+	// Others, if not 0, replaces every cell that isn't highlighted, to pick
+	// out the highlighted ones more clearly.
+	Others rune
+
+	out   io.Writer
+	delay time.Duration
+	next  time.Time
+	// lines drawn by the previous frame, to move back up over.
+	drawn int
+}
+
+// NewTerminal draws to out, at no more than fps frames per second when
+// animating; fps <= 0 means as fast as possible.
+func NewTerminal(out io.Writer, fps int) *Terminal {
+	t := &Terminal{Highlight: map[rune]Colour{}, out: out}
+	if fps > 0 {
+		t.delay = time.Second / time.Duration(fps)
+	}
+	return t
+}
+
+// Render returns g as coloured text, with later overlays drawn on top of
+// earlier ones.
+func (t *Terminal) Render(g *grid.Grid[rune], overlays ...Overlay) string {
+	type over struct {
+		r rune
+		c Colour
+	}
+	overs := map[grid.Point]over{}
+	for _, o := range overlays {
+		for _, p := range o.Cells {
+			overs[p] = over{o.Rune, o.Colour}
+		}
+	}
+
+	var s strings.Builder
+	for y, row := range g.Cells {
+		for x, r := range row {
+			c, ok := t.Highlight[r]
+			if !ok && t.Others != 0 {
+				r = t.Others
+			}
+			if o, ok := overs[grid.Point{X: x, Y: y}]; ok {
+				if o.r != 0 {
+					r = o.r
+				}
+				c = o.c
+			}
+			s.WriteString(c.wrap(string(r)))
+		}
+		s.WriteRune('\n')
+	}
+	return s.String()
+}
+
+// Draw writes a frame, over the top of the previous one if there was one,
+// waiting first if needed to keep to the frame rate.
+func (t *Terminal) Draw(g *grid.Grid[rune], overlays ...Overlay) {
+	t.DrawWithStatus(g, "", overlays...)
+}
+
+// DrawWithStatus is Draw with a line of text under the grid.
+func (t *Terminal) DrawWithStatus(g *grid.Grid[rune], status string, overlays ...Overlay) {
+	if t.delay > 0 {
+		if wait := time.Until(t.next); wait > 0 {
+			time.Sleep(wait)
+		}
+		t.next = time.Now().Add(t.delay)
+	}
+	var s strings.Builder
+	if t.drawn == 0 {
+		s.WriteString(hideCursor)
+	} else {
+		s.WriteString(fmt.Sprintf("%s%dA\r", csi, t.drawn))
+	}
+	s.WriteString(t.Render(g, overlays...))
+	s.WriteString(csi + "2K" + status + "\n")
+	io.WriteString(t.out, s.String())
+	t.drawn = g.H + 1
+}
+
+// Close puts the cursor back; call it once the animation is over.
+func (t *Terminal) Close() {
+	if t.drawn > 0 {
+		io.WriteString(t.out, showCursor)
+	}
+}
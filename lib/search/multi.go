@@ -0,0 +1,211 @@
+package search
+
+import "container/heap"
+
+// TargetsResult is the cost from the nearest start to each target reached.
+type TargetsResult[S comparable] struct {
+	Costs    map[S]int
+	Expanded int
+}
+
+// AllFound reports whether every one of targets was reached.
+func (r TargetsResult[S]) AllFound(targets []S) bool {
+	for _, t := range targets {
+		if _, ok := r.Costs[t]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// SolveTargets searches out from p's starts for every one of targets, and
+// stops as soon as the last of them is reached rather than going on to
+// search the whole space. p.Goal is ignored. BFS counts moves; Dijkstra
+// and A* (which has no single goal to aim for, so is Dijkstra here) add up
+// costs.
+func SolveTargets[S comparable](alg Algorithm, p Problem[S], targets []S) TargetsResult[S] {
+	if alg == BFS {
+		p = unitCosts(p)
+	}
+	res := TargetsResult[S]{Costs: map[S]int{}}
+	left := map[S]bool{}
+	for _, t := range targets {
+		left[t] = true
+	}
+	cost := map[S]int{}
+	pq := &queue[S]{}
+	for _, s := range p.starts() {
+		cost[s] = 0
+		heap.Push(pq, item[S]{s: s})
+	}
+	done := map[S]bool{}
+	for pq.Len() > 0 && len(left) > 0 {
+		it := heap.Pop(pq).(item[S])
+		if done[it.s] || it.cost > cost[it.s] {
+			continue
+		}
+		done[it.s] = true
+		if left[it.s] {
+			res.Costs[it.s] = it.cost
+			delete(left, it.s)
+			if len(left) == 0 {
+				break
+			}
+		}
+		res.Expanded++
+		if p.Observer != nil {
+			p.Observer(it.s, it.cost, pq.states)
+		}
+		for _, e := range p.Neighbours(it.s) {
+			c := it.cost + e.Cost
+			if old, seen := cost[e.To]; seen && old <= c {
+				continue
+			}
+			cost[e.To] = c
+			heap.Push(pq, item[S]{s: e.To, cost: c, priority: c})
+		}
+	}
+	return res
+}
+
+// SolveBidirectional searches forwards from p.Start and backwards from
+// goal at the same time, and stops once the two searches meet in the
+// middle, which on open grids looks at far fewer states than searching
+// from one end. p.Goal, p.Starts and p.Heuristic are ignored; A* is
+// treated as Dijkstra.
+func SolveBidirectional[S comparable](alg Algorithm, p Problem[S], goal S) Result[S] {
+	back := p.Predecessors
+	if back == nil {
+		back = p.Neighbours
+	}
+	if alg == BFS {
+		return biBFS(p, back, goal)
+	}
+	return biDijkstra(p, back, goal)
+}
+
+// side is one of the two searches in a bidirectional search.
+type side[S comparable] struct {
+	next func(S) []Edge[S]
+	cost map[S]int
+	// via maps each state to the one it was reached from, towards this
+	// side's end.
+	via map[S]S
+}
+
+func newSide[S comparable](from S, next func(S) []Edge[S]) *side[S] {
+	return &side[S]{next: next, cost: map[S]int{from: 0}, via: map[S]S{}}
+}
+
+// join puts the path together from where the sides meet.
+func join[S comparable](fwd, bwd *side[S], meet S) []S {
+	path := walkBack(fwd.via, meet)
+	for s, ok := bwd.via[meet]; ok; s, ok = bwd.via[s] {
+		path = append(path, s)
+	}
+	return path
+}
+
+// biBFS grows each side a whole level at a time, always the side with the
+// smaller frontier. The first level to touch the other side holds the
+// meeting point, but not necessarily at its first touch, so the whole
+// level is looked at before stopping.
+func biBFS[S comparable](p Problem[S], back func(S) []Edge[S], goal S) Result[S] {
+	var res Result[S]
+	fwd, bwd := newSide(p.Start, p.Neighbours), newSide(goal, back)
+	if p.Start == goal {
+		res.Found, res.Path = true, []S{goal}
+		return res
+	}
+	fwdFront, bwdFront := []S{p.Start}, []S{goal}
+	for len(fwdFront) > 0 && len(bwdFront) > 0 {
+		this, other, front := fwd, bwd, &fwdFront
+		if len(bwdFront) < len(fwdFront) {
+			this, other, front = bwd, fwd, &bwdFront
+		}
+		var next []S
+		best, meet := -1, goal
+		for _, s := range *front {
+			res.Expanded++
+			if p.Observer != nil {
+				p.Observer(s, this.cost[s], nil)
+			}
+			for _, e := range this.next(s) {
+				if _, seen := this.cost[e.To]; seen {
+					continue
+				}
+				this.cost[e.To] = this.cost[s] + 1
+				this.via[e.To] = s
+				next = append(next, e.To)
+				if c, ok := other.cost[e.To]; ok && (best < 0 || this.cost[e.To]+c < best) {
+					best, meet = this.cost[e.To]+c, e.To
+				}
+			}
+		}
+		if best >= 0 {
+			res.Found, res.Cost, res.Path = true, best, join(fwd, bwd, meet)
+			return res
+		}
+		*front = next
+	}
+	return res
+}
+
+// biDijkstra settles states from whichever side has the cheaper state
+// waiting. It keeps the cheapest path seen joining the two sides, and
+// stops once no path through the states still waiting could beat it.
+func biDijkstra[S comparable](p Problem[S], back func(S) []Edge[S], goal S) Result[S] {
+	var res Result[S]
+	fwd, bwd := newSide(p.Start, p.Neighbours), newSide(goal, back)
+	fq, bq := &queue[S]{}, &queue[S]{}
+	heap.Push(fq, item[S]{s: p.Start})
+	heap.Push(bq, item[S]{s: goal})
+	fdone, bdone := map[S]bool{}, map[S]bool{}
+	best, meet := -1, goal
+	if p.Start == goal {
+		best, meet = 0, goal
+	}
+	// top is the cost of the next live entry in q, dropping stale ones.
+	top := func(q *queue[S], sd *side[S], done map[S]bool) (int, bool) {
+		for q.Len() > 0 {
+			it := (*q)[0]
+			if !done[it.s] && it.cost <= sd.cost[it.s] {
+				return it.cost, true
+			}
+			heap.Pop(q)
+		}
+		return 0, false
+	}
+	for {
+		ft, fok := top(fq, fwd, fdone)
+		bt, bok := top(bq, bwd, bdone)
+		if !fok || !bok || (best >= 0 && ft+bt >= best) {
+			break
+		}
+		this, other, q, done := fwd, bwd, fq, fdone
+		if bt < ft {
+			this, other, q, done = bwd, fwd, bq, bdone
+		}
+		it := heap.Pop(q).(item[S])
+		done[it.s] = true
+		res.Expanded++
+		if p.Observer != nil {
+			p.Observer(it.s, it.cost, q.states)
+		}
+		for _, e := range this.next(it.s) {
+			c := it.cost + e.Cost
+			if old, seen := this.cost[e.To]; !seen || c < old {
+				this.cost[e.To] = c
+				this.via[e.To] = it.s
+				heap.Push(q, item[S]{s: e.To, cost: c, priority: c})
+			}
+			if oc, ok := other.cost[e.To]; ok && this.cost[e.To] == c && (best < 0 || c+oc < best) {
+				best, meet = c+oc, e.To
+			}
+		}
+	}
+	if best >= 0 {
+		res.Found, res.Cost, res.Path = true, best, join(fwd, bwd, meet)
+	}
+	return res
+}
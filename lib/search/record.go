@@ -0,0 +1,50 @@
+package search
+
+// Recording keeps what a search did, for drawing or replaying afterwards:
+// the order it expanded states in, the cost it had reached each at, and
+// the path or paths it settled on.
+type Recording[S comparable] struct {
+	// Order lists states as they were expanded. A state expanded more than
+	// once, as day 10's route enumeration does, appears each time.
+	Order []S
+	// Costs parallels Order.
+	Costs []int
+	// Paths are the final answers, added by the caller with AddPath.
+	Paths [][]S
+	// Snapshots of the frontier, taken every Every expansions if Every > 0.
+	Every     int
+	Snapshots []Snapshot[S]
+}
+
+// Snapshot is the frontier as it was when Step states had been expanded.
+type Snapshot[S comparable] struct {
+	Step     int
+	Frontier []S
+}
+
+// Observe records one expansion. It has the shape of Problem.Observer, and
+// frontier may be nil for searches that don't have one to show.
+func (r *Recording[S]) Observe(s S, cost int, frontier func() []S) {
+	r.Order = append(r.Order, s)
+	r.Costs = append(r.Costs, cost)
+	if r.Every > 0 && frontier != nil && len(r.Order)%r.Every == 0 {
+		r.Snapshots = append(r.Snapshots, Snapshot[S]{Step: len(r.Order), Frontier: frontier()})
+	}
+}
+
+func (r *Recording[S]) AddPath(path []S) {
+	r.Paths = append(r.Paths, path)
+}
+
+// Record returns p with its Observer wrapped to also record into rec. Any
+// Observer p already had still gets called.
+func Record[S comparable](p Problem[S], rec *Recording[S]) Problem[S] {
+	next := p.Observer
+	p.Observer = func(s S, cost int, frontier func() []S) {
+		rec.Observe(s, cost, frontier)
+		if next != nil {
+			next(s, cost, frontier)
+		}
+	}
+	return p
+}
@@ -0,0 +1,325 @@
+// Package search finds cheapest paths through state spaces. Callers
+// describe the space as a Problem; BFS, Dijkstra and A* all solve the same
+// Problem and return the same Result.
+package search
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+// Edge is a move to state To costing Cost.
+type Edge[S comparable] struct {
+	To   S
+	Cost int
+}
+
+type Problem[S comparable] struct {
+	Start S
+	// Starts, if set, are several states to search out from at once, all
+	// at cost 0, and Start is ignored. Paths begin at whichever is nearest.
+	Starts []S
+	// Neighbours lists the moves out of s. Costs must not be negative.
+	Neighbours func(s S) []Edge[S]
+	Goal       func(s S) bool
+	// Heuristic, if set, is used by A*. It must be consistent: never more
+	// than a move's cost plus the heuristic where the move lands, and 0 at
+	// a goal. A* here doesn't look at a state again once it's expanded, so
+	// a heuristic that only never overestimates isn't enough, and can have
+	// it return a more expensive path.
+	Heuristic func(s S) int
+	// Observer, if set, is called as each state is expanded, with its cost
+	// so far.  frontier lists the states still waiting to be expanded.
+	Observer func(s S, cost int, frontier func() []S)
+	// Predecessors lists the moves into s, for searching backwards from a
+	// goal. If it's nil, moves are taken to be reversible, so it's the
+	// same as Neighbours.
+	Predecessors func(s S) []Edge[S]
+}
+
+// starts lists where the search begins.
+func (p Problem[S]) starts() []S {
+	if len(p.Starts) > 0 {
+		return p.Starts
+	}
+	return []S{p.Start}
+}
+
+type Algorithm int
+
+const (
+	// BFS ignores edge costs and finds the path with fewest moves; its
+	// Cost is the number of moves.
+	BFS Algorithm = iota
+	Dijkstra
+	AStar
+)
+
+func (a Algorithm) String() string {
+	return map[Algorithm]string{
+		BFS:      "bfs",
+		Dijkstra: "dijkstra",
+		AStar:    "astar",
+	}[a]
+}
+
+func ParseAlgorithm(s string) (Algorithm, error) {
+	for _, a := range []Algorithm{BFS, Dijkstra, AStar} {
+		if a.String() == s {
+			return a, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown search algorithm %q, want bfs, dijkstra or astar", s)
+}
+
+type Result[S comparable] struct {
+	Found bool
+	Cost  int
+	// Path runs from the start to the goal inclusive.
+	Path []S
+	// Expanded is how many states had their neighbours looked at.
+	Expanded int
+}
+
+func (r Result[S]) String() string {
+	if !r.Found {
+		return fmt.Sprintf("<no path, %d expanded>", r.Expanded)
+	}
+	return fmt.Sprintf("<cost %d, %d steps, %d expanded>", r.Cost, len(r.Path)-1, r.Expanded)
+}
+
+// Solve searches p with the given algorithm.
+func Solve[S comparable](alg Algorithm, p Problem[S]) Result[S] {
+	switch alg {
+	case BFS:
+		return bfs(p)
+	case AStar:
+		return best(p, p.Heuristic)
+	default:
+		return best(p, nil)
+	}
+}
+
+func bfs[S comparable](p Problem[S]) Result[S] {
+	var res Result[S]
+	prev := map[S]S{}
+	depth := map[S]int{}
+	var queue []S
+	for _, s := range p.starts() {
+		if _, seen := depth[s]; !seen {
+			depth[s] = 0
+			queue = append(queue, s)
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		if p.Goal(s) {
+			res.Found, res.Cost, res.Path = true, depth[s], walkBack(prev, s)
+			return res
+		}
+		res.Expanded++
+		if p.Observer != nil {
+			p.Observer(s, depth[s], func() []S { return append([]S(nil), queue...) })
+		}
+		for _, e := range p.Neighbours(s) {
+			if _, seen := depth[e.To]; seen {
+				continue
+			}
+			depth[e.To] = depth[s] + 1
+			prev[e.To] = s
+			queue = append(queue, e.To)
+		}
+	}
+	return res
+}
+
+// best is Dijkstra, or A* when h is set.
+func best[S comparable](p Problem[S], h func(S) int) Result[S] {
+	if h == nil {
+		h = func(S) int { return 0 }
+	}
+	var res Result[S]
+	prev := map[S]S{}
+	cost := map[S]int{}
+	done := map[S]bool{}
+	pq := &queue[S]{}
+	for _, s := range p.starts() {
+		cost[s] = 0
+		heap.Push(pq, item[S]{s: s, cost: 0, priority: h(s)})
+	}
+	for pq.Len() > 0 {
+		it := heap.Pop(pq).(item[S])
+		if done[it.s] || it.cost > cost[it.s] {
+			// A stale entry: the state was reached more cheaply since.
+			continue
+		}
+		done[it.s] = true
+		if p.Goal(it.s) {
+			res.Found, res.Cost, res.Path = true, it.cost, walkBack(prev, it.s)
+			return res
+		}
+		res.Expanded++
+		if p.Observer != nil {
+			p.Observer(it.s, it.cost, pq.states)
+		}
+		for _, e := range p.Neighbours(it.s) {
+			c := it.cost + e.Cost
+			if old, seen := cost[e.To]; seen && old <= c {
+				continue
+			}
+			cost[e.To] = c
+			prev[e.To] = it.s
+			heap.Push(pq, item[S]{s: e.To, cost: c, priority: c + h(e.To)})
+		}
+	}
+	return res
+}
+
+// walkBack follows prev from end back to whichever start it came from.
+func walkBack[S comparable](prev map[S]S, end S) []S {
+	path := []S{end}
+	for s, ok := prev[end]; ok; s, ok = prev[s] {
+		path = append(path, s)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+type item[S comparable] struct {
+	s        S
+	cost     int
+	priority int
+}
+
+// queue is a min-heap of items by priority, for container/heap.
+type queue[S comparable] []item[S]
+
+func (q queue[S]) Len() int           { return len(q) }
+func (q queue[S]) Less(i, j int) bool { return q[i].priority < q[j].priority }
+func (q queue[S]) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *queue[S]) Push(x any)        { *q = append(*q, x.(item[S])) }
+
+// states lists what's queued, skipping stale duplicates.
+func (q queue[S]) states() []S {
+	seen := map[S]bool{}
+	var ss []S
+	for _, it := range q {
+		if !seen[it.s] {
+			seen[it.s] = true
+			ss = append(ss, it.s)
+		}
+	}
+	return ss
+}
+
+func (q *queue[S]) Pop() any {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
+
+// AllResult describes every cheapest path to a goal, rather than just one.
+type AllResult[S comparable] struct {
+	Found bool
+	Cost  int
+	// Goals are the goal states reached at the best cost.
+	Goals []S
+	// Preds holds, for each state reached, every state it can be reached
+	// from at its best cost: the cheapest paths form a DAG over them.
+	Preds    map[S][]S
+	Expanded int
+}
+
+// SolveAll is Solve but keeps all the cheapest paths to any goal. For A*
+// the heuristic must also be consistent (it never drops by more than the
+// cost of an edge), otherwise some paths can be missed.
+func SolveAll[S comparable](alg Algorithm, p Problem[S]) AllResult[S] {
+	var h func(S) int
+	switch alg {
+	case BFS:
+		p = unitCosts(p)
+	case AStar:
+		h = p.Heuristic
+	}
+	if h == nil {
+		h = func(S) int { return 0 }
+	}
+
+	res := AllResult[S]{Preds: map[S][]S{}}
+	cost := map[S]int{}
+	done := map[S]bool{}
+	pq := &queue[S]{}
+	for _, s := range p.starts() {
+		cost[s] = 0
+		heap.Push(pq, item[S]{s: s, cost: 0, priority: h(s)})
+	}
+	for pq.Len() > 0 {
+		it := heap.Pop(pq).(item[S])
+		if done[it.s] || it.cost > cost[it.s] {
+			continue
+		}
+		if res.Found && it.priority > res.Cost {
+			// Nothing left can reach a goal as cheaply.
+			break
+		}
+		done[it.s] = true
+		if p.Goal(it.s) {
+			res.Found, res.Cost = true, it.cost
+			res.Goals = append(res.Goals, it.s)
+			continue
+		}
+		res.Expanded++
+		if p.Observer != nil {
+			p.Observer(it.s, it.cost, pq.states)
+		}
+		for _, e := range p.Neighbours(it.s) {
+			c := it.cost + e.Cost
+			old, seen := cost[e.To]
+			switch {
+			case !seen || c < old:
+				cost[e.To] = c
+				res.Preds[e.To] = []S{it.s}
+				heap.Push(pq, item[S]{s: e.To, cost: c, priority: c + h(e.To)})
+			case c == old:
+				res.Preds[e.To] = append(res.Preds[e.To], it.s)
+			}
+		}
+	}
+	return res
+}
+
+// OnBestPaths walks the predecessor DAG back from the goals, and returns
+// every state that's on at least one cheapest path.
+func (r AllResult[S]) OnBestPaths() []S {
+	seen := map[S]bool{}
+	var states []S
+	todo := append([]S(nil), r.Goals...)
+	for len(todo) > 0 {
+		s := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		states = append(states, s)
+		todo = append(todo, r.Preds[s]...)
+	}
+	return states
+}
+
+func unitCosts[S comparable](p Problem[S]) Problem[S] {
+	next := p.Neighbours
+	p.Neighbours = func(s S) []Edge[S] {
+		// A copy, as the caller's Neighbours may hand back edges it keeps.
+		es := append([]Edge[S](nil), next(s)...)
+		for i := range es {
+			es[i].Cost = 1
+		}
+		return es
+	}
+	return p
+}
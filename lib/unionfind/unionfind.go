@@ -0,0 +1,124 @@
+// Package unionfind is a disjoint-set forest with path compression and
+// union by rank, so Find and Union are effectively constant time.
+package unionfind
+
+// DisjointSet partitions the keys added to it into disjoint sets.
+// The zero value is not usable; call New.
+type DisjointSet[K comparable] struct {
+	parent map[K]K
+	rank   map[K]int
+	size   map[K]int
+	// keys in insertion order, so enumeration is deterministic.
+	keys  []K
+	count int
+}
+
+func New[K comparable]() *DisjointSet[K] {
+	return &DisjointSet[K]{
+		parent: map[K]K{},
+		rank:   map[K]int{},
+		size:   map[K]int{},
+	}
+}
+
+// Add puts k in a set of its own, unless it's already present.
+func (d *DisjointSet[K]) Add(k K) {
+	if _, ok := d.parent[k]; ok {
+		return
+	}
+	d.parent[k] = k
+	d.size[k] = 1
+	d.keys = append(d.keys, k)
+	d.count++
+}
+
+func (d *DisjointSet[K]) Has(k K) bool {
+	_, ok := d.parent[k]
+	return ok
+}
+
+// Find returns the representative of the set holding k, adding k first if
+// it's new, so it counts towards Len and Count from then on.
+func (d *DisjointSet[K]) Find(k K) K {
+	d.Add(k)
+	root := k
+	for d.parent[root] != root {
+		root = d.parent[root]
+	}
+	// Path compression: point everything we walked through at the root.
+	for k != root {
+		next := d.parent[k]
+		d.parent[k] = root
+		k = next
+	}
+	return root
+}
+
+// Union merges the sets holding a and b, and returns false if they were
+// already the same set.
+func (d *DisjointSet[K]) Union(a, b K) bool {
+	ra, rb := d.Find(a), d.Find(b)
+	if ra == rb {
+		return false
+	}
+	// Union by rank: hang the shallower tree under the deeper one.
+	if d.rank[ra] < d.rank[rb] {
+		ra, rb = rb, ra
+	}
+	d.parent[rb] = ra
+	d.size[ra] += d.size[rb]
+	delete(d.size, rb)
+	if d.rank[ra] == d.rank[rb] {
+		d.rank[ra]++
+	}
+	delete(d.rank, rb)
+	d.count--
+	return true
+}
+
+// Same reports whether a and b are in the same set. Unlike Find it only
+// looks: a key that hasn't been added isn't in any set, so isn't the same
+// as anything, and isn't added.
+func (d *DisjointSet[K]) Same(a, b K) bool {
+	if !d.Has(a) || !d.Has(b) {
+		return false
+	}
+	return d.Find(a) == d.Find(b)
+}
+
+// Len is the number of keys added.
+func (d *DisjointSet[K]) Len() int {
+	return len(d.keys)
+}
+
+// Count is the number of disjoint sets.
+func (d *DisjointSet[K]) Count() int {
+	return d.count
+}
+
+// Size is the number of keys in the set holding k, or 0 if k hasn't been
+// added, which Size doesn't do.
+func (d *DisjointSet[K]) Size(k K) int {
+	if !d.Has(k) {
+		return 0
+	}
+	return d.size[d.Find(k)]
+}
+
+// Components lists every set. Sets are ordered by their earliest added
+// key, and keys within a set keep their insertion order.
+func (d *DisjointSet[K]) Components() [][]K {
+	idx := map[K]int{}
+	var ret [][]K
+	for _, k := range d.keys {
+		r := d.Find(k)
+		i, ok := idx[r]
+		if !ok {
+			i = len(ret)
+			idx[r] = i
+			ret = append(ret, make([]K, 0, d.size[r]))
+		}
+		ret[i] = append(ret[i], k)
+	}
+	return ret
+}